@@ -0,0 +1,214 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"io"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLogEntry is a connection recorded in the Xray access log.
+type AccessLogEntry struct {
+	Time time.Time
+	// Source address without the port.
+	Source     string
+	SourcePort int
+	Accepted   bool
+	// "tcp" or "udp".
+	Network         string
+	Destination     string
+	DestinationPort int
+	Inbound         string
+	Outbound        string
+	Email           string
+	// Why Xray rejected the connection.
+	Reason string
+}
+
+var (
+	// from 10.0.0.2:51234 accepted tcp:example.com:443 [vless-in >> direct] email: alice
+	accessLogMessage = regexp.MustCompile(`^from (\S+) (accepted|rejected)\s+(.*)$`)
+	accessLogTarget  = regexp.MustCompile(`^(\S+)(?: \[([^\]]*)\])?(?: email: (\S+))?$`)
+)
+
+// ParseAccessLogLine parses an Xray access log line. It reports false for
+// lines that are not connections, such as DNS queries or error log lines.
+func ParseAccessLogLine(line string) (AccessLogEntry, bool) {
+	record := ParseLogLine(line)
+	m := accessLogMessage.FindStringSubmatch(record.Message)
+	if m == nil || record.Level != "" {
+		return AccessLogEntry{}, false
+	}
+	entry := AccessLogEntry{Time: record.Time, Accepted: m[2] == "accepted"}
+	_, entry.Source, entry.SourcePort = splitLogAddr(m[1])
+	if !entry.Accepted {
+		entry.Reason = m[3]
+		return entry, true
+	}
+
+	t := accessLogTarget.FindStringSubmatch(m[3])
+	if t == nil {
+		return AccessLogEntry{}, false
+	}
+	entry.Network, entry.Destination, entry.DestinationPort = splitLogAddr(t[1])
+	entry.Email = t[3]
+	detour := t[2]
+	for _, sep := range []string{" >> ", " -> "} {
+		if in, out, ok := strings.Cut(detour, sep); ok {
+			entry.Inbound, entry.Outbound = in, out
+			return entry, true
+		}
+	}
+	entry.Outbound = detour
+	return entry, true
+}
+
+// splitLogAddr splits "tcp:example.com:443" or "10.0.0.2:51234". Addresses
+// it can't split are returned as the host.
+func splitLogAddr(addr string) (network, host string, port int) {
+	for _, n := range []string{"tcp:", "udp:"} {
+		if rest, ok := strings.CutPrefix(addr, n); ok {
+			network, addr = strings.TrimSuffix(n, ":"), rest
+			break
+		}
+	}
+	h, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return network, addr, 0
+	}
+	port, _ = strconv.Atoi(p)
+	return network, h, port
+}
+
+// ReadAccessLog calls fn with each connection in an Xray access log, such
+// as the file set in XrayLog.Access. Other lines are skipped.
+func ReadAccessLog(r io.Reader, fn func(AccessLogEntry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := ParseAccessLogLine(scanner.Text()); ok {
+			fn(entry)
+		}
+	}
+	return scanner.Err()
+}
+
+// GetAccessLog returns the connections in the last count lines of the Xray
+// log, read through the panel.
+func (c *Client) GetAccessLog(ctx context.Context, count int, filter XrayLogFilter) ([]AccessLogEntry, error) {
+	lines, err := c.xrayLogLines(ctx, count, filter)
+	if err != nil {
+		return nil, err
+	}
+	var entries []AccessLogEntry
+	for _, line := range lines {
+		if entry, ok := ParseAccessLogLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// DestinationCount is the number of connections to a destination.
+type DestinationCount struct {
+	Destination string
+	Count       int
+}
+
+// AccessLogStats aggregates accepted connections by client email and
+// outbound tag. Connections without an email are counted under "".
+type AccessLogStats struct {
+	destinations map[string]map[string]int
+	sources      map[string]map[string]struct{}
+	outbounds    map[string]int
+}
+
+// NewAccessLogStats returns empty stats.
+func NewAccessLogStats() *AccessLogStats {
+	return &AccessLogStats{
+		destinations: make(map[string]map[string]int),
+		sources:      make(map[string]map[string]struct{}),
+		outbounds:    make(map[string]int),
+	}
+}
+
+// Add counts entry if it was accepted.
+func (s *AccessLogStats) Add(entry AccessLogEntry) {
+	if !entry.Accepted {
+		return
+	}
+	if s.destinations[entry.Email] == nil {
+		s.destinations[entry.Email] = make(map[string]int)
+		s.sources[entry.Email] = make(map[string]struct{})
+	}
+	s.destinations[entry.Email][entry.Destination]++
+	s.sources[entry.Email][entry.Source] = struct{}{}
+	s.outbounds[entry.Outbound]++
+}
+
+// Emails returns the sorted client emails seen.
+func (s *AccessLogStats) Emails() []string {
+	emails := make([]string, 0, len(s.destinations))
+	for email := range s.destinations {
+		emails = append(emails, email)
+	}
+	slices.Sort(emails)
+	return emails
+}
+
+// TopDestinations returns the n destinations email connected to most,
+// ordered by count then name. n <= 0 returns all of them.
+func (s *AccessLogStats) TopDestinations(email string, n int) []DestinationCount {
+	counts := make([]DestinationCount, 0, len(s.destinations[email]))
+	for dest, count := range s.destinations[email] {
+		counts = append(counts, DestinationCount{Destination: dest, Count: count})
+	}
+	slices.SortFunc(counts, func(a, b DestinationCount) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Destination, b.Destination)
+	})
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// SourceIPs returns the sorted distinct source addresses of email.
+func (s *AccessLogStats) SourceIPs(email string) []string {
+	ips := make([]string, 0, len(s.sources[email]))
+	for ip := range s.sources[email] {
+		ips = append(ips, ip)
+	}
+	slices.Sort(ips)
+	return ips
+}
+
+// OutboundConnections returns the number of connections per outbound tag.
+func (s *AccessLogStats) OutboundConnections() map[string]int {
+	return maps.Clone(s.outbounds)
+}
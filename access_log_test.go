@@ -0,0 +1,84 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseAccessLogLine(t *testing.T) {
+	entry, ok := ParseAccessLogLine("2024/05/01 12:00:04.000400 from [2001:db8::1]:6000 accepted tcp:torrent.example:6881 [vmess-in -> blocked] email: bob")
+	expected := AccessLogEntry{
+		Time:            time.Date(2024, 5, 1, 12, 0, 4, 400000, time.UTC),
+		Source:          "2001:db8::1",
+		SourcePort:      6000,
+		Accepted:        true,
+		Network:         "tcp",
+		Destination:     "torrent.example",
+		DestinationPort: 6881,
+		Inbound:         "vmess-in",
+		Outbound:        "blocked",
+		Email:           "bob",
+	}
+	if !ok || entry != expected {
+		t.Errorf("Expected %+v, got %+v", expected, entry)
+	}
+
+	entry, ok = ParseAccessLogLine("2024/05/01 12:00:05 from 198.51.100.7:1111 rejected  proxy/vless/encoding: invalid request user id")
+	if !ok || entry.Accepted || entry.Source != "198.51.100.7" || entry.Reason != "proxy/vless/encoding: invalid request user id" {
+		t.Errorf("Unexpected rejected entry %+v", entry)
+	}
+
+	if _, ok := ParseAccessLogLine("2024/05/01 12:00:06 [Info] app/dispatcher: taking detour [direct]"); ok {
+		t.Error("Expected error log line to be skipped")
+	}
+}
+
+func TestAccessLogStats(t *testing.T) {
+	f, err := os.Open("testdata/access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stats := NewAccessLogStats()
+	if err := ReadAccessLog(f, stats.Add); err != nil {
+		t.Fatal(err)
+	}
+
+	if emails := stats.Emails(); !slices.Equal(emails, []string{"", "alice", "bob"}) {
+		t.Errorf("Unexpected emails %q", emails)
+	}
+	top := stats.TopDestinations("alice", 1)
+	if len(top) != 1 || top[0] != (DestinationCount{Destination: "example.com", Count: 2}) {
+		t.Errorf("Unexpected top destinations %+v", top)
+	}
+	if len(stats.TopDestinations("alice", 0)) != 2 {
+		t.Errorf("Expected all destinations with n = 0")
+	}
+	if ips := stats.SourceIPs("alice"); !slices.Equal(ips, []string{"10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("Unexpected source IPs %q", ips)
+	}
+	expected := map[string]int{"direct": 3, "blocked": 1, "api": 1}
+	if outbounds := stats.OutboundConnections(); !maps.Equal(outbounds, expected) {
+		t.Errorf("Expected %v, got %v", expected, outbounds)
+	}
+}
@@ -36,16 +36,22 @@ type ClientSettings struct {
 }
 
 type XrayClient struct {
-	ID         string `json:"id"`
-	AlterID    uint   `json:"alter_id,omitempty"`
-	Email      string `json:"email"`
-	Flow       string `json:"flow"`
-	LimitIP    uint   `json:"limitIp"`
-	TotalGB    uint64 `json:"totalGB"`
-	ExpiryTime uint64 `json:"expiryTime"`
-	Enable     bool   `json:"enable"`
-	TgID       uint   `json:"tgId"`
-	SubID      string `json:"subId"`
+	ID      string `json:"id"`
+	AlterID uint   `json:"alter_id,omitempty"`
+	// Password of trojan and shadowsocks clients.
+	Password string `json:"password,omitempty"`
+	// Cipher of vmess clients.
+	Security string `json:"security,omitempty"`
+	Email    string `json:"email"`
+	Flow     string `json:"flow"`
+	LimitIP  uint   `json:"limitIp"`
+	// Traffic quota in bytes, zero means unlimited.
+	TotalGB    ByteSize `json:"totalGB"`
+	ExpiryTime Expiry   `json:"expiryTime"`
+	Enable     bool     `json:"enable"`
+	TgID       uint     `json:"tgId"`
+	SubID      string   `json:"subId"`
+	Comment    string   `json:"comment,omitempty"`
 }
 
 // Add client to an inbound.
@@ -0,0 +1,287 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Files written to each backup directory.
+const (
+	BackupDBFile            = "x-ui.db"
+	BackupPanelSettingsFile = "panel-settings.json"
+	BackupXraySettingsFile  = "xray-settings.json"
+	BackupChecksumsFile     = "SHA256SUMS"
+)
+
+// backupTimeFormat names backup directories, in UTC. Nanoseconds keep runs
+// within the same second apart; parsing with backupParseFormat also accepts
+// the names without them that older versions wrote.
+const (
+	backupTimeFormat  = "20060102T150405.000000000Z"
+	backupParseFormat = "20060102T150405Z"
+)
+
+// BackupRetention is how many backups to keep per panel: the newest of
+// each of the last Daily days and of the last Weekly ISO weeks. Both zero
+// keeps everything.
+type BackupRetention struct {
+	Daily  int
+	Weekly int
+}
+
+// BackupResult is the outcome of backing up one panel.
+type BackupResult struct {
+	Url string
+	// Backup directory, empty if the backup failed.
+	Dir string
+	// Backup directories deleted by the retention policy.
+	Pruned []string
+	Err    error
+}
+
+// BackupRunner backs up panels into Dir/<panel>/<time>/, where panel is
+// derived from the Config.Url and time is the UTC start of the backup.
+// Each backup holds the database, the panel and Xray settings as JSON and
+// a SHA256SUMS file in sha256sum format.
+type BackupRunner struct {
+	Dir       string
+	Panels    []Config
+	Retention BackupRetention
+
+	clients map[string]*Client
+}
+
+// Run backs up every panel once. A failing panel doesn't stop the others,
+// its error is in its result.
+func (r *BackupRunner) Run(ctx context.Context) []BackupResult {
+	if r.clients == nil {
+		r.clients = make(map[string]*Client)
+	}
+	results := make([]BackupResult, 0, len(r.Panels))
+	for _, cfg := range r.Panels {
+		result := BackupResult{Url: cfg.Url}
+		c, ok := r.clients[cfg.Url]
+		if !ok {
+			c = New(cfg)
+			r.clients[cfg.Url] = c
+		}
+		panelDir := filepath.Join(r.Dir, backupPanelName(cfg.Url))
+		result.Dir, result.Err = backupPanel(ctx, c, panelDir, time.Now())
+		if result.Err == nil {
+			result.Pruned, result.Err = PruneBackups(panelDir, r.Retention)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// RunEvery calls Run every interval, starting immediately, and passes the
+// results to fn. It returns ctx.Err() once ctx is done, or an error right
+// away if interval isn't positive.
+func (r *BackupRunner) RunEvery(ctx context.Context, interval time.Duration, fn func([]BackupResult)) error {
+	if err := checkPollInterval(interval); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		results := r.Run(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fn(results)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// backupPanel writes a backup of c into a new directory under panelDir.
+// The directory only gets its final name once complete.
+func backupPanel(ctx context.Context, c *Client, panelDir string, now time.Time) (string, error) {
+	dir := filepath.Join(panelDir, now.UTC().Format(backupTimeFormat))
+	tmp := dir + ".partial"
+	if err := os.MkdirAll(panelDir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(tmp, 0o700); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	sums := map[string]string{}
+	err := writeBackupFile(tmp, BackupDBFile, sums, func(w io.Writer) error {
+		_, err := c.DownloadDB(ctx, w)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download database: %w", err)
+	}
+
+	panelSettings, err := c.GetPanelSettings(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get panel settings: %w", err)
+	}
+	if err := writeBackupJSON(tmp, BackupPanelSettingsFile, sums, panelSettings.Obj); err != nil {
+		return "", err
+	}
+	xraySettings, err := c.GetXraySettings(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get xray settings: %w", err)
+	}
+	if err := writeBackupJSON(tmp, BackupXraySettingsFile, sums, xraySettings); err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, BackupChecksumsFile), []byte(b.String()), 0o600); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func writeBackupJSON(dir, name string, sums map[string]string, v interface{}) error {
+	return writeBackupFile(dir, name, sums, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// writeBackupFile creates dir/name with the content written by write and
+// records its SHA256 in sums.
+func writeBackupFile(dir, name string, sums map[string]string, write func(io.Writer) error) error {
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if err := write(io.MultiWriter(f, h)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	sums[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// backupPanelName turns a panel URL into a directory name, e.g.
+// "xrayserver.tld_8843" for "https://xrayserver.tld:8843".
+func backupPanelName(panelURL string) string {
+	name := panelURL
+	if u, err := url.Parse(panelURL); err == nil && u.Host != "" {
+		name = u.Host + strings.TrimSuffix(u.Path, "/")
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// PruneBackups deletes the backup directories in panelDir that keep
+// doesn't retain and returns their paths. Other files are left alone.
+func PruneBackups(panelDir string, keep BackupRetention) ([]string, error) {
+	if keep.Daily <= 0 && keep.Weekly <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(panelDir)
+	if err != nil {
+		return nil, err
+	}
+	var times []time.Time
+	names := map[time.Time]string{}
+	for _, e := range entries {
+		if t, err := time.Parse(backupParseFormat, e.Name()); err == nil && e.IsDir() {
+			times = append(times, t)
+			names[t] = e.Name()
+		}
+	}
+
+	retained := retainedBackups(times, keep)
+	var pruned []string
+	var errs []error
+	for _, t := range times {
+		if retained[t] {
+			continue
+		}
+		dir := filepath.Join(panelDir, names[t])
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pruned = append(pruned, dir)
+	}
+	return pruned, errors.Join(errs...)
+}
+
+// retainedBackups returns the backup times keep retains: the newest backup
+// of each of the newest keep.Daily days and keep.Weekly ISO weeks.
+func retainedBackups(times []time.Time, keep BackupRetention) map[time.Time]bool {
+	sorted := slices.Clone(times)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return b.Compare(a) })
+
+	retained := map[time.Time]bool{}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	for _, t := range sorted {
+		day := t.Format("2006-01-02")
+		if !days[day] && len(days) < keep.Daily {
+			days[day] = true
+			retained[t] = true
+		}
+		year, week := t.ISOWeek()
+		key := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[key] && len(weeks) < keep.Weekly {
+			weeks[key] = true
+			retained[t] = true
+		}
+	}
+	return retained
+}
@@ -0,0 +1,204 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetainedBackups(t *testing.T) {
+	// Sunday 2024-05-12 back to Monday 2024-04-22, twice a day.
+	start := time.Date(2024, 5, 12, 18, 0, 0, 0, time.UTC)
+	var times []time.Time
+	for i := 0; i < 21; i++ {
+		day := start.AddDate(0, 0, -i)
+		times = append(times, day, day.Add(-12*time.Hour))
+	}
+
+	retained := retainedBackups(times, BackupRetention{Daily: 3, Weekly: 3})
+	expected := []time.Time{
+		start,                    // newest of 05-12 and of week 19
+		start.AddDate(0, 0, -1),  // 05-11
+		start.AddDate(0, 0, -2),  // 05-10
+		start.AddDate(0, 0, -7),  // 05-05, week 18
+		start.AddDate(0, 0, -14), // 04-28, week 17
+	}
+	if len(retained) != len(expected) {
+		t.Errorf("Expected %d backups retained, got %d: %v", len(expected), len(retained), retained)
+	}
+	for _, e := range expected {
+		if !retained[e] {
+			t.Errorf("Expected %s to be retained", e)
+		}
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"20240512T180000.000000001Z", "20240512T060000Z", "20240511T180000Z", "notes"}
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := PruneBackups(dir, BackupRetention{Daily: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "20240511T180000Z"), filepath.Join(dir, "20240512T060000Z")}
+	slices.Sort(pruned)
+	if !slices.Equal(pruned, expected) {
+		t.Errorf("Expected %q pruned, got %q", expected, pruned)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes")); err != nil {
+		t.Errorf("Expected other directories to be kept: %v", err)
+	}
+}
+
+func TestBackupPanelName(t *testing.T) {
+	tests := map[string]string{
+		"https://xrayserver.tld:8843":         "xrayserver.tld_8843",
+		"https://xrayserver.tld:8843/secret/": "xrayserver.tld_8843_secret",
+		"http://[2001:db8::1]:2053":           "_2001_db8__1__2053",
+	}
+	for in, expected := range tests {
+		if name := backupPanelName(in); name != expected {
+			t.Errorf("backupPanelName(%q) = %q, expected %q", in, name, expected)
+		}
+	}
+}
+
+func TestBackupRunEveryInterval(t *testing.T) {
+	r := &BackupRunner{Dir: t.TempDir()}
+	for _, interval := range []time.Duration{0, -time.Hour} {
+		err := r.RunEvery(context.Background(), interval, func([]BackupResult) {
+			t.Error("Expected no backup run")
+		})
+		if err == nil {
+			t.Errorf("Expected an error for interval %v", interval)
+		}
+	}
+}
+
+func TestBackupRunner(t *testing.T) {
+	db := sqliteHeader + "pages"
+	p := newFakePanel(t)
+	p.handle("/server/getDb", func(*http.Request) (int, []byte) {
+		return http.StatusOK, []byte(db)
+	})
+	p.handleObj("/panel/setting/all", PanelSettings{WebPort: 2053, WebBasePath: "/secret/"})
+	p.handleObj("/panel/xray/", `{"xraySetting":{"log":{"loglevel":"warning"}},"inboundTags":["vless-reality"]}`)
+
+	r := &BackupRunner{
+		Dir:    t.TempDir(),
+		Panels: []Config{{Url: fakePanelURL, Username: "admin", Password: "admin", Client: &http.Client{Transport: p}}},
+	}
+	panelDir := filepath.Join(r.Dir, "panel.test")
+
+	// Two runs within the same second get their own directories.
+	first := r.Run(context.Background())
+	second := r.Run(context.Background())
+	for _, results := range [][]BackupResult{first, second} {
+		if len(results) != 1 || results[0].Err != nil {
+			t.Fatalf("Unexpected results %+v", results)
+		}
+		if filepath.Dir(results[0].Dir) != panelDir {
+			t.Errorf("Expected the backup in %s, got %s", panelDir, results[0].Dir)
+		}
+	}
+	if first[0].Dir == second[0].Dir {
+		t.Fatalf("Expected two backup directories, got %s twice", first[0].Dir)
+	}
+
+	dir := second[0].Dir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	expected := []string{BackupChecksumsFile, BackupPanelSettingsFile, BackupXraySettingsFile, BackupDBFile}
+	slices.Sort(expected)
+	if !slices.Equal(files, expected) {
+		t.Errorf("Expected files %q, got %q", expected, files)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, BackupDBFile)); string(b) != db {
+		t.Errorf("Expected the database, got %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, BackupXraySettingsFile)); !strings.Contains(string(b), "vless-reality") {
+		t.Errorf("Expected the Xray settings, got %s", b)
+	}
+
+	sums, err := os.ReadFile(filepath.Join(dir, BackupChecksumsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	for _, name := range []string{BackupPanelSettingsFile, BackupDBFile, BackupXraySettingsFile} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		fmt.Fprintf(&want, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	if string(sums) != want.String() {
+		t.Errorf("Expected checksums\n%s\ngot\n%s", want.String(), sums)
+	}
+
+	// A failed download leaves no partial backup behind.
+	p.handle("/server/getDb", func(*http.Request) (int, []byte) {
+		return apiFailure("session expired")
+	})
+	failed := r.Run(context.Background())
+	if len(failed) != 1 || failed[0].Err == nil || failed[0].Dir != "" {
+		t.Fatalf("Expected a failed backup, got %+v", failed)
+	}
+	entries, err = os.ReadDir(panelDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the two backups, got %v", entries)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".partial") {
+			t.Errorf("Expected %s to be removed", e.Name())
+		}
+	}
+
+	pruned, err := PruneBackups(panelDir, BackupRetention{Daily: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned, []string{first[0].Dir}) {
+		t.Errorf("Expected %s pruned, got %q", first[0].Dir, pruned)
+	}
+}
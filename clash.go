@@ -0,0 +1,307 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ClashOptions control the profile built by ClashProfile.
+type ClashOptions struct {
+	// Port of the mixed HTTP/SOCKS inbound, 7890 if zero.
+	MixedPort int
+	// Names of the select and url-test groups, "PROXY" and "AUTO" if empty.
+	SelectGroup  string
+	URLTestGroup string
+	// Health check of the url-test group. Defaults to
+	// https://www.gstatic.com/generate_204 every 5 minutes.
+	TestURL      string
+	TestInterval time.Duration
+	// Rule sets, matched in order before Rules.
+	RuleProviders []ClashRuleProvider
+	// Extra rules such as "DOMAIN-SUFFIX,example.com,DIRECT". A final
+	// "MATCH,<SelectGroup>" is always added.
+	Rules []string
+}
+
+// ClashRuleProvider is a remote rule set of a Clash profile.
+type ClashRuleProvider struct {
+	Name string
+	// "domain", "ipcidr" or "classical".
+	Behavior string
+	// "yaml", "text" or "mrs", yaml if empty.
+	Format string
+	URL    string
+	// Update interval, 24 hours if zero.
+	Interval time.Duration
+	// Policy of matching connections, e.g. "DIRECT", "REJECT" or a group.
+	Policy string
+	// Don't resolve domains to match an ipcidr set.
+	NoResolve bool
+}
+
+// ClashProxies converts outbounds into a Clash/Mihomo "proxies:" document.
+// Outbounds Clash can't represent are returned in skipped.
+func ClashProxies(outbounds []XrayOutbound) (yaml []byte, skipped []SkippedOutbound) {
+	proxies, _, skipped := clashProxies(outbounds, map[string]bool{})
+	return encodeYAML(yamlMap{{"proxies", proxies}}), skipped
+}
+
+// ClashProfile builds a complete Clash/Mihomo profile from outbounds, with a
+// select group, a url-test group over all proxies and the given rule sets.
+// Outbounds Clash can't represent are returned in skipped, it is an error if
+// none are left.
+func ClashProfile(outbounds []XrayOutbound, opts ClashOptions) ([]byte, []SkippedOutbound, error) {
+	if opts.MixedPort == 0 {
+		opts.MixedPort = 7890
+	}
+	opts.SelectGroup = valueOr(opts.SelectGroup, "PROXY")
+	opts.URLTestGroup = valueOr(opts.URLTestGroup, "AUTO")
+	opts.TestURL = valueOr(opts.TestURL, "https://www.gstatic.com/generate_204")
+	if opts.TestInterval == 0 {
+		opts.TestInterval = 5 * time.Minute
+	}
+
+	taken := map[string]bool{
+		"DIRECT": true, "REJECT": true,
+		opts.SelectGroup: true, opts.URLTestGroup: true,
+	}
+	proxies, names, skipped := clashProxies(outbounds, taken)
+	if len(proxies) == 0 {
+		return nil, skipped, errors.New("no outbounds can be represented in Clash")
+	}
+
+	groups := []yamlMap{
+		{
+			{"name", opts.SelectGroup},
+			{"type", "select"},
+			{"proxies", append(append([]string{opts.URLTestGroup}, names...), "DIRECT")},
+		},
+		{
+			{"name", opts.URLTestGroup},
+			{"type", "url-test"},
+			{"proxies", names},
+			{"url", opts.TestURL},
+			{"interval", int(opts.TestInterval / time.Second)},
+			{"tolerance", 50},
+		},
+	}
+
+	providers := yamlMap{}
+	var rules []string
+	for _, rp := range opts.RuleProviders {
+		if rp.Name == "" || rp.URL == "" || rp.Behavior == "" || rp.Policy == "" {
+			return nil, skipped, fmt.Errorf("rule provider %q needs a name, behavior, URL and policy", rp.Name)
+		}
+		format := valueOr(rp.Format, "yaml")
+		interval := rp.Interval
+		if interval == 0 {
+			interval = 24 * time.Hour
+		}
+		ext := format
+		if format == "text" {
+			ext = "txt"
+		}
+		providers.set(rp.Name, yamlMap{
+			{"type", "http"},
+			{"behavior", rp.Behavior},
+			{"format", format},
+			{"url", rp.URL},
+			{"path", "./ruleset/" + rp.Name + "." + ext},
+			{"interval", int(interval / time.Second)},
+		})
+		rule := "RULE-SET," + rp.Name + "," + rp.Policy
+		if rp.NoResolve {
+			rule += ",no-resolve"
+		}
+		rules = append(rules, rule)
+	}
+	rules = append(rules, opts.Rules...)
+	rules = append(rules, "MATCH,"+opts.SelectGroup)
+
+	profile := yamlMap{
+		{"mixed-port", opts.MixedPort},
+		{"allow-lan", false},
+		{"mode", "rule"},
+		{"log-level", "info"},
+		{"proxies", proxies},
+		{"proxy-groups", groups},
+	}
+	profile.set("rule-providers", providers)
+	profile.set("rules", rules)
+	return encodeYAML(profile), skipped, nil
+}
+
+func clashProxies(outbounds []XrayOutbound, taken map[string]bool) ([]yamlMap, []string, []SkippedOutbound) {
+	var proxies []yamlMap
+	var names []string
+	var skipped []SkippedOutbound
+	for _, o := range outbounds {
+		proxy, err := clashProxy(o, taken)
+		if err != nil {
+			skipped = append(skipped, SkippedOutbound{Tag: o.Tag, Err: err})
+			continue
+		}
+		proxies = append(proxies, proxy)
+		names = append(names, proxy[0].value.(string))
+	}
+	return proxies, names, skipped
+}
+
+// clashProxy converts one outbound. The name is always the first field.
+func clashProxy(o XrayOutbound, taken map[string]bool) (yamlMap, error) {
+	p, err := decodeProxyOutbound(o)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := yamlMap{{"name", ""}}
+	switch p.Protocol {
+	case "vless":
+		if p.Encryption != "" && p.Encryption != "none" {
+			return nil, fmt.Errorf("VLESS encryption %q is not supported by Clash", p.Encryption)
+		}
+		proxy.set("type", "vless")
+		proxy.set("server", p.Address)
+		proxy.set("port", p.Port)
+		proxy.set("uuid", p.ID)
+		proxy.set("flow", p.Flow)
+	case "vmess":
+		proxy.set("type", "vmess")
+		proxy.set("server", p.Address)
+		proxy.set("port", p.Port)
+		proxy.set("uuid", p.ID)
+		proxy.set("alterId", p.AlterID)
+		proxy.set("cipher", valueOr(p.Cipher, "auto"))
+	case "trojan":
+		if p.Security == "none" {
+			return nil, errors.New("trojan without TLS is not supported by Clash")
+		}
+		proxy.set("type", "trojan")
+		proxy.set("server", p.Address)
+		proxy.set("port", p.Port)
+		proxy.set("password", p.Password)
+	case "shadowsocks":
+		cipher, ok := ssCiphers[p.Method]
+		if !ok {
+			return nil, fmt.Errorf("shadowsocks method %q is not supported by Clash", p.Method)
+		}
+		if p.Network != "tcp" || p.Security != "none" || p.HeaderType != "" {
+			return nil, errors.New("shadowsocks with a transport or TLS is not supported by Clash")
+		}
+		proxy.set("type", "ss")
+		proxy.set("server", p.Address)
+		proxy.set("port", p.Port)
+		proxy.set("cipher", cipher)
+		proxy.set("password", p.Password)
+		proxy.set("udp", true)
+	}
+	if p.Protocol != "shadowsocks" {
+		proxy.set("udp", true)
+		if err := clashTransport(&proxy, p); err != nil {
+			return nil, err
+		}
+	}
+	proxy[0].value = uniqueName(proxyName(p), taken)
+	return proxy, nil
+}
+
+// clashTransport adds the TLS, Reality and network options of p.
+func clashTransport(proxy *yamlMap, p *proxyOutbound) error {
+	switch p.Security {
+	case "none":
+	case "tls", "reality":
+		if p.Protocol != "trojan" {
+			proxy.set("tls", true)
+		}
+		// Trojan calls the server name sni.
+		if p.Protocol == "trojan" {
+			proxy.set("sni", p.ServerName)
+		} else {
+			proxy.set("servername", p.ServerName)
+		}
+		proxy.set("alpn", p.Alpn)
+		if p.AllowInsecure {
+			proxy.set("skip-cert-verify", true)
+		}
+		fingerprint := p.Fingerprint
+		if p.Security == "reality" {
+			fingerprint = valueOr(fingerprint, "chrome")
+			opts := yamlMap{{"public-key", p.PublicKey}}
+			opts.set("short-id", p.ShortID)
+			proxy.set("reality-opts", opts)
+		}
+		proxy.set("client-fingerprint", fingerprint)
+	default:
+		return fmt.Errorf("security %q is not supported by Clash", p.Security)
+	}
+
+	switch p.Network {
+	case "tcp":
+		if p.HeaderType != "http" {
+			return nil
+		}
+		if p.Protocol == "trojan" {
+			return errors.New("trojan with HTTP header obfuscation is not supported by Clash")
+		}
+		proxy.set("network", "http")
+		opts := yamlMap{}
+		opts.set("method", p.HeaderMethod)
+		opts.set("path", valueOrList(p.HeaderPaths, "/"))
+		keys := make([]string, 0, len(p.HeaderHeaders))
+		for k := range p.HeaderHeaders {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		headers := yamlMap{}
+		for _, k := range keys {
+			headers.set(k, p.HeaderHeaders[k])
+		}
+		opts.set("headers", headers)
+		proxy.set("http-opts", opts)
+	case "ws", "httpupgrade":
+		proxy.set("network", "ws")
+		opts := yamlMap{{"path", valueOr(p.Path, "/")}}
+		if p.Host != "" {
+			opts.set("headers", yamlMap{{"Host", p.Host}})
+		}
+		if p.Network == "httpupgrade" {
+			opts.set("v2ray-http-upgrade", true)
+		}
+		proxy.set("ws-opts", opts)
+	case "grpc":
+		// Clash has no option for the :authority of gRPC requests.
+		if p.Host != "" {
+			return errors.New("gRPC authority is not supported by Clash")
+		}
+		proxy.set("network", "grpc")
+		proxy.set("grpc-opts", yamlMap{{"grpc-service-name", p.ServiceName}})
+	default:
+		return fmt.Errorf("transport %q is not supported by Clash", p.Network)
+	}
+	return nil
+}
+
+func valueOrList(v []string, def string) []string {
+	if len(v) == 0 {
+		return []string{def}
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testExportOutbounds returns VLESS-Reality, VMess-WS, Trojan-gRPC and SS
+// outbounds followed by two that no client format can represent.
+func testExportOutbounds(t *testing.T) []XrayOutbound {
+	t.Helper()
+	clients, _ := testRealityInbound.Clients()
+	outbounds, err := testRealityInbound.Outbounds(clients[0], ShareLinkOptions{Address: "89.169.53.31"})
+	if err != nil {
+		t.Fatalf("Outbounds() error = %v", err)
+	}
+	vmessJSON := `{"v":"2","ps":"vm","add":"example.com","port":"443","id":"b831381d-6324-4d53-ad4f-8cda48b30811","aid":"0","scy":"auto","net":"ws","host":"cdn.example.com","path":"/ws","tls":"tls","sni":"example.com","fp":"chrome"}`
+	for _, link := range []string{
+		"vmess://" + base64.StdEncoding.EncodeToString([]byte(vmessJSON)),
+		"trojan://secret@1.2.3.4:8443?type=grpc&serviceName=svc&security=tls&sni=example.com&alpn=h2#tr",
+		"ss://" + base64.RawURLEncoding.EncodeToString([]byte("2022-blake3-aes-128-gcm:a2V5MQ==")) + "@example.com:8388#ss",
+		"vless://uuid@example.com:443?type=kcp&security=none#kcp",
+	} {
+		outbound, err := ParseShareLink(link)
+		if err != nil {
+			t.Fatalf("ParseShareLink(%q) error = %v", link, err)
+		}
+		outbounds = append(outbounds, *outbound)
+	}
+	return append(outbounds, *CreateFreedomOutbound("direct", "UseIP"))
+}
+
+func TestClashProfile(t *testing.T) {
+	profile, skipped, err := ClashProfile(testExportOutbounds(t), ClashOptions{
+		RuleProviders: []ClashRuleProvider{{
+			Name:     "ads",
+			Behavior: "domain",
+			Format:   "mrs",
+			URL:      "https://example.com/ads.mrs",
+			Policy:   "REJECT",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("ClashProfile() error = %v", err)
+	}
+	want, err := os.ReadFile("testdata/clash_profile.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(profile) != string(want) {
+		t.Errorf("Unexpected profile:\n%s", profile)
+	}
+	if len(skipped) != 2 || skipped[0].Tag != "kcp" || skipped[1].Tag != "direct" {
+		t.Errorf("Expected kcp and direct to be skipped, got %v", skipped)
+	}
+}
+
+func TestClashProxiesNames(t *testing.T) {
+	outbound, _ := ParseShareLink("trojan://secret@1.2.3.4:443")
+	proxies, _ := ClashProxies([]XrayOutbound{*outbound, *outbound})
+	if !strings.Contains(string(proxies), `name: "trojan-1.2.3.4:443 2"`) {
+		t.Errorf("Expected unique generated names, got:\n%s", proxies)
+	}
+	if _, _, err := ClashProfile(nil, ClashOptions{}); err == nil {
+		t.Error("Expected error for a profile without proxies")
+	}
+}
+
+func TestClashProxiesGrpcAuthority(t *testing.T) {
+	outbound, err := ParseShareLink("trojan://secret@1.2.3.4:8443?type=grpc&serviceName=svc&authority=grpc.example.com&security=tls&sni=example.com#tr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, skipped := ClashProxies([]XrayOutbound{*outbound})
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Err.Error(), "authority") {
+		t.Errorf("Expected the gRPC authority to be flagged, got %v", skipped)
+	}
+}
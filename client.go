@@ -27,6 +27,10 @@ type Client struct {
 	httpClient         *http.Client
 	sessionCookie      *http.Cookie
 	sessionExpires     time.Time
+	// Subscription base URLs and encoding discovered from the panel
+	// settings when Config.SubUrl is empty.
+	subURI, subJsonURI string
+	subEncoding        SubscriptionEncoding
 }
 
 func New(c Config) *Client {
@@ -0,0 +1,188 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The panel answers with this message instead of a list when nothing was logged.
+const noIPRecord = "No IP Record"
+
+// ClientIP is an address the panel has seen a client connect from.
+type ClientIP struct {
+	IP string
+	// Last time the address was seen. Zero if the panel version doesn't
+	// record timestamps. The panel formats times in its own time zone,
+	// they are interpreted as UTC here.
+	Time time.Time
+}
+
+// IPLimitViolation is a client that connected from more distinct addresses
+// than its LimitIP allows.
+type IPLimitViolation struct {
+	InboundID int
+	Email     string
+	LimitIP   int
+	IPs       []ClientIP
+}
+
+// GetClientIPs returns the addresses the panel has recorded for a client.
+func (c *Client) GetClientIPs(ctx context.Context, email string) ([]ClientIP, error) {
+	resp := &ApiResponse{}
+	err := c.Do(ctx, http.MethodPost, "/panel/api/inbounds/clientIps/"+url.PathEscape(email), nil, resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%s", resp.Msg)
+	}
+	return parseClientIPs(resp.Obj)
+}
+
+// ClearClientIPs removes the addresses recorded for a client.
+func (c *Client) ClearClientIPs(ctx context.Context, email string) error {
+	resp := &ApiResponse{}
+	err := c.Do(ctx, http.MethodPost, "/panel/api/inbounds/clearClientIps/"+url.PathEscape(email), nil, resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
+
+// ClientsOverIPLimit lists clients with a LimitIP whose recorded distinct
+// addresses exceed it. Clients without a limit are skipped.
+func (c *Client) ClientsOverIPLimit(ctx context.Context) ([]IPLimitViolation, error) {
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var violations []IPLimitViolation
+	for _, inbound := range inbounds.Obj {
+		clients, err := inbound.Clients()
+		if err != nil {
+			return nil, fmt.Errorf("inbound %d: %w", inbound.ID, err)
+		}
+		for _, client := range clients {
+			if client.LimitIp <= 0 {
+				continue
+			}
+			ips, err := c.GetClientIPs(ctx, client.Email)
+			if err != nil {
+				return nil, fmt.Errorf("client %s: %w", client.Email, err)
+			}
+			distinct := distinctClientIPs(ips)
+			if len(distinct) > client.LimitIp {
+				violations = append(violations, IPLimitViolation{
+					InboundID: inbound.ID,
+					Email:     client.Email,
+					LimitIP:   client.LimitIp,
+					IPs:       distinct,
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// distinctClientIPs keeps the most recent entry of every address.
+func distinctClientIPs(ips []ClientIP) []ClientIP {
+	index := make(map[string]int)
+	var out []ClientIP
+	for _, ip := range ips {
+		if i, ok := index[ip.IP]; ok {
+			if ip.Time.After(out[i].Time) {
+				out[i] = ip
+			}
+			continue
+		}
+		index[ip.IP] = len(out)
+		out = append(out, ip)
+	}
+	return out
+}
+
+// parseClientIPs understands every format the panel has used: a JSON list
+// encoded into a string or sent as is, containing either plain addresses,
+// "1.2.3.4 (2006-01-02 15:04:05)" strings or {"ip", "timestamp"} objects.
+func parseClientIPs(obj json.RawMessage) ([]ClientIP, error) {
+	if len(obj) == 0 || string(obj) == "null" {
+		return nil, nil
+	}
+	list := []byte(obj)
+	var str string
+	if err := json.Unmarshal(obj, &str); err == nil {
+		str = strings.TrimSpace(str)
+		if str == "" || str == noIPRecord {
+			return nil, nil
+		}
+		list = []byte(str)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(list, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse client IPs: %w", err)
+	}
+	ips := make([]ClientIP, 0, len(entries))
+	for _, entry := range entries {
+		var s string
+		if err := json.Unmarshal(entry, &s); err == nil {
+			ip, err := parseClientIPString(s)
+			if err != nil {
+				return nil, err
+			}
+			ips = append(ips, ip)
+			continue
+		}
+		var withTime struct {
+			IP        string `json:"ip"`
+			Timestamp int64  `json:"timestamp"`
+		}
+		if err := json.Unmarshal(entry, &withTime); err != nil {
+			return nil, fmt.Errorf("failed to parse client IP entry %s: %w", entry, err)
+		}
+		ip := ClientIP{IP: withTime.IP}
+		if withTime.Timestamp > 0 {
+			ip.Time = time.Unix(withTime.Timestamp, 0).UTC()
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+func parseClientIPString(s string) (ClientIP, error) {
+	s = strings.TrimSpace(s)
+	addr, rest, found := strings.Cut(s, " ")
+	if !found {
+		return ClientIP{IP: s}, nil
+	}
+	rest = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(rest), "("), ")")
+	t, err := time.Parse(time.DateTime, rest)
+	if err != nil {
+		return ClientIP{}, fmt.Errorf("invalid client IP entry %q: %w", s, err)
+	}
+	return ClientIP{IP: addr, Time: t}, nil
+}
@@ -0,0 +1,86 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseClientIPs(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		want []ClientIP
+	}{
+		{name: "No record", obj: `"No IP Record"`},
+		{name: "Null", obj: `null`},
+		{
+			name: "Plain addresses in string",
+			obj:  `"[\"1.2.3.4\",\"2001:db8::1\"]"`,
+			want: []ClientIP{{IP: "1.2.3.4"}, {IP: "2001:db8::1"}},
+		},
+		{
+			name: "Addresses with time",
+			obj:  `"[\"1.2.3.4 (2025-01-02 03:04:05)\"]"`,
+			want: []ClientIP{{IP: "1.2.3.4", Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}},
+		},
+		{
+			name: "Objects",
+			obj:  `[{"ip":"5.6.7.8","timestamp":1735787045}]`,
+			want: []ClientIP{{IP: "5.6.7.8", Time: time.Unix(1735787045, 0).UTC()}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClientIPs(json.RawMessage(tt.obj))
+			if err != nil {
+				t.Fatalf("parseClientIPs() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d entries, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if got[i].IP != tt.want[i].IP || !got[i].Time.Equal(tt.want[i].Time) {
+					t.Errorf("Entry %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+
+	if _, err := parseClientIPs(json.RawMessage(`"not a list"`)); err == nil {
+		t.Error("Expected error for malformed list")
+	}
+}
+
+func TestDistinctClientIPs(t *testing.T) {
+	early := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	got := distinctClientIPs([]ClientIP{
+		{IP: "1.1.1.1", Time: early},
+		{IP: "2.2.2.2"},
+		{IP: "1.1.1.1", Time: late},
+	})
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 distinct addresses, got %d", len(got))
+	}
+	if !got[0].Time.Equal(late) {
+		t.Errorf("Expected most recent time to be kept, got %v", got[0].Time)
+	}
+}
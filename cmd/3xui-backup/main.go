@@ -0,0 +1,92 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command 3xui-backup backs up 3X-UI panels into a directory, once or on a
+// schedule. Panels are read from a JSON file:
+//
+//	[{"url": "https://xrayserver.tld:8843", "username": "digilol", "password": "secr3t"}]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nextster/client3xui"
+)
+
+func main() {
+	configPath := flag.String("config", "panels.json", "JSON file listing the panels")
+	dir := flag.String("dir", "backups", "directory to store the backups in")
+	daily := flag.Int("daily", 7, "number of daily backups to keep")
+	weekly := flag.Int("weekly", 4, "number of weekly backups to keep")
+	interval := flag.Duration("interval", 0, "time between backups, 0 backs up once")
+	flag.Parse()
+	if *interval < 0 {
+		log.Fatalf("interval must not be negative, got %v", *interval)
+	}
+
+	b, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var panels []client3xui.Config
+	if err := json.Unmarshal(b, &panels); err != nil {
+		log.Fatalf("failed to parse %s: %v", *configPath, err)
+	}
+
+	runner := &client3xui.BackupRunner{
+		Dir:       *dir,
+		Panels:    panels,
+		Retention: client3xui.BackupRetention{Daily: *daily, Weekly: *weekly},
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *interval == 0 {
+		if !report(runner.Run(ctx)) {
+			os.Exit(1)
+		}
+		return
+	}
+	err = runner.RunEvery(ctx, *interval, func(results []client3xui.BackupResult) {
+		report(results)
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
+
+// report logs the results and reports whether all backups succeeded.
+func report(results []client3xui.BackupResult) bool {
+	ok := true
+	for _, r := range results {
+		if r.Err != nil {
+			log.Printf("%s: %v", r.Url, r.Err)
+			ok = false
+			continue
+		}
+		log.Printf("%s: backed up to %s", r.Url, r.Dir)
+		for _, dir := range r.Pruned {
+			log.Printf("%s: pruned %s", r.Url, dir)
+		}
+	}
+	return ok
+}
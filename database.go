@@ -0,0 +1,125 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// sqliteHeader starts every SQLite 3 database file.
+const sqliteHeader = "SQLite format 3\x00"
+
+// ErrNotSQLite is returned for data that isn't an SQLite database.
+var ErrNotSQLite = errors.New("not an SQLite database")
+
+// DownloadDB streams the panel database to w and returns the number of
+// bytes written. Nothing is written unless the download starts with the
+// SQLite header.
+func (c *Client) DownloadDB(ctx context.Context, w io.Writer) (int64, error) {
+	resp, err := c.doStream(ctx, http.MethodGet, "/server/getDb", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	r, err := sqliteReader(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, r)
+}
+
+// ImportDB uploads a database to replace the panel's. The panel restarts
+// Xray with it. Use RestoreDB to verify the data and wait for the panel. r
+// is not read anymore once ImportDB returns.
+func (c *Client) ImportDB(ctx context.Context, r io.Reader) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		part, err := mw.CreateFormFile("db", "x-ui.db")
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := c.doStream(ctx, http.MethodPost, "/server/importDB", mw.FormDataContentType(), pr)
+	// Unblocks the writer if the request stopped reading the body, and
+	// waits for it so r is no longer read once ImportDB returns.
+	pr.Close()
+	<-done
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	apiResp := &ApiResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(apiResp); err != nil {
+		return err
+	}
+	if !apiResp.Success {
+		return fmt.Errorf("%s", apiResp.Msg)
+	}
+	return nil
+}
+
+// RestoreDB checks that r is an SQLite database, imports it, logs in again
+// since the restored database has its own session secret and users, and
+// waits until Xray is running. Errors from waiting are *XrayStateError.
+func (c *Client) RestoreDB(ctx context.Context, r io.Reader) (*ServerStatus, error) {
+	r, err := sqliteReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ImportDB(ctx, r); err != nil {
+		return nil, err
+	}
+
+	c.sessionCookie = nil
+	if err := c.login(ctx); err != nil {
+		return nil, fmt.Errorf("failed to log in after restore: %w", err)
+	}
+	// The restored settings may serve subscriptions elsewhere.
+	c.subURI, c.subJsonURI, c.subEncoding = "", "", SubEncodingAuto
+	return c.WaitForXrayState(ctx, Running)
+}
+
+// sqliteReader returns a reader of all of r after checking that it starts
+// with the SQLite header.
+func sqliteReader(r io.Reader) (io.Reader, error) {
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotSQLite
+		}
+		return nil, err
+	}
+	if string(header) != sqliteHeader {
+		return nil, ErrNotSQLite
+	}
+	return io.MultiReader(bytes.NewReader(header), r), nil
+}
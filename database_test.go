@@ -0,0 +1,180 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSqliteReader(t *testing.T) {
+	db := sqliteHeader + "\x10\x00\x01\x01rest of the pages"
+	r, err := sqliteReader(strings.NewReader(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != db {
+		t.Errorf("Expected the whole database back, got %q", b)
+	}
+
+	for _, data := range []string{"", "SQLite", `{"success":false,"msg":"not logged in"}`} {
+		if _, err := sqliteReader(strings.NewReader(data)); !errors.Is(err, ErrNotSQLite) {
+			t.Errorf("Expected ErrNotSQLite for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestDownloadDB(t *testing.T) {
+	db := sqliteHeader + "pages"
+	p := newFakePanel(t)
+	p.handle("/server/getDb", func(*http.Request) (int, []byte) {
+		return http.StatusOK, []byte(db)
+	})
+
+	var buf bytes.Buffer
+	n, err := p.client().DownloadDB(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(db)) || buf.String() != db {
+		t.Errorf("Expected the database, got %d bytes %q", n, buf.String())
+	}
+
+	// A panel that lost the session answers with JSON.
+	p.handle("/server/getDb", func(*http.Request) (int, []byte) {
+		return apiFailure("session expired")
+	})
+	buf.Reset()
+	if _, err := p.client().DownloadDB(context.Background(), &buf); !errors.Is(err, ErrNotSQLite) {
+		t.Errorf("Expected ErrNotSQLite, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written, got %q", buf.String())
+	}
+}
+
+func TestImportDB(t *testing.T) {
+	db := sqliteHeader + strings.Repeat("page", 10000)
+	p := newFakePanel(t)
+	p.handle("/server/importDB", func(r *http.Request) (int, []byte) {
+		file, header, err := r.FormFile("db")
+		if err != nil {
+			t.Errorf("Expected a db form file: %v", err)
+			return apiFailure(err.Error())
+		}
+		defer file.Close()
+		b, _ := io.ReadAll(file)
+		if header.Filename != "x-ui.db" || string(b) != db {
+			t.Errorf("Unexpected upload %q of %d bytes", header.Filename, len(b))
+		}
+		return apiSuccess(nil)
+	})
+
+	if err := p.client().ImportDB(context.Background(), strings.NewReader(db)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// slowReader returns "first", then signals reading and takes a while to
+// return more. It reports reads that end after done is set.
+type slowReader struct {
+	t       *testing.T
+	reads   int
+	reading chan struct{}
+	done    atomic.Bool
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	r.reads++
+	if r.reads == 1 {
+		return copy(b, "first"), nil
+	}
+	if r.reads == 2 {
+		close(r.reading)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if r.done.Load() {
+		r.t.Error("Read after ImportDB returned")
+	}
+	return copy(b, "more"), nil
+}
+
+func TestImportDBStopsReading(t *testing.T) {
+	r := &slowReader{t: t, reading: make(chan struct{})}
+	p := newFakePanel(t)
+	// The panel fails partway through the upload, while r is being read.
+	p.handle("/server/importDB", func(req *http.Request) (int, []byte) {
+		var body []byte
+		buf := make([]byte, 4096)
+		for !bytes.Contains(body, []byte("first")) {
+			n, err := req.Body.Read(buf)
+			if err != nil {
+				break
+			}
+			body = append(body, buf[:n]...)
+		}
+		<-r.reading
+		return http.StatusInternalServerError, nil
+	})
+
+	if err := p.client().ImportDB(context.Background(), r); err == nil {
+		t.Error("Expected an error")
+	}
+	r.done.Store(true)
+	time.Sleep(40 * time.Millisecond)
+}
+
+func TestRestoreDB(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleObj("/server/importDB", nil)
+	p.handleStatuses(XrayStatus{State: Running})
+	c := p.client()
+	c.subURI, c.subJsonURI, c.subEncoding = "https://sub.example.com/sub/", "https://sub.example.com/json/", SubEncodingBase64
+
+	if _, err := c.RestoreDB(context.Background(), strings.NewReader("not a database at all")); !errors.Is(err, ErrNotSQLite) {
+		t.Errorf("Expected ErrNotSQLite, got %v", err)
+	}
+	if p.callCount("/server/importDB") != 0 {
+		t.Error("Expected nothing imported")
+	}
+
+	status, err := c.RestoreDB(context.Background(), strings.NewReader(sqliteHeader+"pages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Xray.State != Running {
+		t.Errorf("Expected Xray running, got %s", status.Xray.State)
+	}
+	if n := p.loginCount(); n != 2 {
+		t.Errorf("Expected a login before and after the import, got %d", n)
+	}
+	if c.subURI != "" || c.subJsonURI != "" || c.subEncoding != SubEncodingAuto {
+		t.Errorf("Expected the discovered subscription settings to be reset, got %q %q %v", c.subURI, c.subJsonURI, c.subEncoding)
+	}
+}
@@ -0,0 +1,208 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrXrayTemplateDefault is returned by DBFile.XraySettings when the
+// database holds no Xray config template, as on a fresh panel whose Xray
+// settings were never saved. The panel uses its built-in template then.
+var ErrXrayTemplateDefault = errors.New("xray config template not set, the panel uses its default")
+
+// DBFile reads a copy of the panel database, x-ui.db, without the panel,
+// returning the same types as the Client. Changes still in the -wal file
+// next to a live database are not seen.
+type DBFile struct {
+	db     *sqliteFile
+	closer io.Closer
+}
+
+// OpenDBFile opens the panel database at path.
+func OpenDBFile(path string) (*DBFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d, err := NewDBFile(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.closer = f
+	return d, nil
+}
+
+// NewDBFile reads a panel database of size bytes from r, such as a backup
+// downloaded with DownloadDB.
+func NewDBFile(r io.ReaderAt, size int64) (*DBFile, error) {
+	db, err := openSQLite(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &DBFile{db: db}, nil
+}
+
+// Close closes the file opened by OpenDBFile.
+func (d *DBFile) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// Inbounds returns the inbounds with their client stats, like GetInbounds.
+func (d *DBFile) Inbounds() ([]Inbound, error) {
+	stats, err := d.ClientStats()
+	if err != nil {
+		return nil, err
+	}
+	byInbound := map[int][]ClientStat{}
+	for _, s := range stats {
+		byInbound[s.InboundID] = append(byInbound[s.InboundID], s)
+	}
+
+	var inbounds []Inbound
+	err = d.rows("inbounds", func(row sqliteRow) error {
+		id := int(row.integer("id"))
+		inbounds = append(inbounds, Inbound{
+			ID:             id,
+			Up:             ByteSize(row.integer("up")),
+			Down:           ByteSize(row.integer("down")),
+			Total:          ByteSize(row.integer("total")),
+			Remark:         row.text("remark"),
+			Enable:         row.boolean("enable"),
+			ExpiryTime:     Expiry(row.integer("expiry_time")),
+			ClientStats:    byInbound[id],
+			Listen:         row.text("listen"),
+			Port:           int(row.integer("port")),
+			Protocol:       row.text("protocol"),
+			Settings:       row.text("settings"),
+			StreamSettings: row.text("stream_settings"),
+			Tag:            row.text("tag"),
+			Sniffing:       row.text("sniffing"),
+		})
+		return nil
+	})
+	return inbounds, err
+}
+
+// ClientStats returns the traffic stats of all clients.
+func (d *DBFile) ClientStats() ([]ClientStat, error) {
+	var stats []ClientStat
+	err := d.rows("client_traffics", func(row sqliteRow) error {
+		stats = append(stats, ClientStat{
+			ID:         int(row.integer("id")),
+			InboundID:  int(row.integer("inbound_id")),
+			Enable:     row.boolean("enable"),
+			Email:      row.text("email"),
+			Up:         ByteSize(row.integer("up")),
+			Down:       ByteSize(row.integer("down")),
+			ExpiryTime: Expiry(row.integer("expiry_time")),
+			Total:      ByteSize(row.integer("total")),
+			Reset:      int(row.integer("reset")),
+		})
+		return nil
+	})
+	return stats, err
+}
+
+// PanelSettings returns the panel settings. Settings the panel never
+// stored are left zero, the panel uses its defaults for them.
+func (d *DBFile) PanelSettings() (*PanelSettings, error) {
+	values, err := d.settings()
+	if err != nil {
+		return nil, err
+	}
+	settings := &PanelSettings{}
+	v := reflect.ValueOf(settings).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		key := t.Field(i).Tag.Get("json")
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("setting %s: %w", key, err)
+			}
+			field.SetInt(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("setting %s: %w", key, err)
+			}
+			field.SetBool(b)
+		}
+	}
+	return settings, nil
+}
+
+// XraySettings returns the Xray config template, the xraySetting of
+// GetXraySettings. It returns ErrXrayTemplateDefault if the panel never
+// stored one.
+func (d *DBFile) XraySettings() (*XraySettings, error) {
+	values, err := d.settings()
+	if err != nil {
+		return nil, err
+	}
+	template, ok := values["xrayTemplateConfig"]
+	if !ok || strings.TrimSpace(template) == "" {
+		return nil, ErrXrayTemplateDefault
+	}
+	var settings XraySettings
+	if err := json.Unmarshal([]byte(template), &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal xray settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (d *DBFile) settings() (map[string]string, error) {
+	values := map[string]string{}
+	err := d.rows("settings", func(row sqliteRow) error {
+		values[row.text("key")] = row.text("value")
+		return nil
+	})
+	return values, err
+}
+
+func (d *DBFile) rows(table string, fn func(sqliteRow) error) error {
+	t, err := d.db.table(table)
+	if err != nil {
+		return err
+	}
+	return d.db.rows(t, fn)
+}
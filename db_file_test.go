@@ -0,0 +1,172 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDBFile(t *testing.T) {
+	d, err := OpenDBFile("testdata/x-ui.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	inbounds, err := d.Inbounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inbounds) != 2 {
+		t.Fatalf("Expected 2 inbounds, got %d", len(inbounds))
+	}
+	reality, ws := inbounds[0], inbounds[1]
+	if reality.ID != 1 || reality.Port != 443 || reality.Protocol != "vless" || !reality.Enable || reality.Down != 5*GiB {
+		t.Errorf("Unexpected inbound %+v", reality)
+	}
+	if ws.Enable || ws.Listen != "127.0.0.1" || ws.Total != 10*GiB || ws.ExpiryTime != 1735689600000 {
+		t.Errorf("Unexpected inbound %+v", ws)
+	}
+	// The settings overflow onto other pages.
+	clients, err := reality.Clients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 150 || clients[149].Email != "user149" {
+		t.Errorf("Expected 150 clients, got %d", len(clients))
+	}
+	if len(reality.ClientStats) != 150 || len(ws.ClientStats) != 0 {
+		t.Errorf("Expected the client stats on the reality inbound, got %d and %d", len(reality.ClientStats), len(ws.ClientStats))
+	}
+
+	// Rows before and after a column was added.
+	stats, err := d.ClientStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range stats {
+		if s.ID != i+1 || s.Email != fmt.Sprintf("user%03d", i) || s.Up != ByteSize(i)*KiB {
+			t.Fatalf("Unexpected client stat %d: %+v", i, s)
+		}
+	}
+	if s := stats[1]; !s.Enable || s.Total != 0 {
+		t.Errorf("Unexpected client stat %+v", s)
+	}
+	if s := stats[120]; s.ExpiryTime != -2592000000 || s.Total != GiB || s.Reset != 30 {
+		t.Errorf("Unexpected client stat %+v", s)
+	}
+
+	settings, err := d.PanelSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.WebPort != 2053 || settings.WebBasePath != "/panel/" || !settings.SubEnable || settings.SubEncrypt || settings.SubPath != "/s/" {
+		t.Errorf("Unexpected panel settings %+v", settings)
+	}
+
+	xray, err := d.XraySettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xray.Outbounds) != 2 || xray.Routing == nil || len(xray.Routing.Rules) != 3 || len(xray.Routing.Rules[2].Domain) != 60 {
+		t.Errorf("Unexpected xray settings %+v", xray)
+	}
+}
+
+func TestNewDBFileNotSQLite(t *testing.T) {
+	data := []byte(`{"success":false}`)
+	if _, err := NewDBFile(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNotSQLite) {
+		t.Errorf("Expected ErrNotSQLite, got %v", err)
+	}
+}
+
+func TestSqliteColumns(t *testing.T) {
+	columns, defaults, rowid, err := sqliteColumns("CREATE TABLE \"t\" (\n\t[a b] TEXT DEFAULT 'it''s',\n\tid INTEGER NOT NULL,\n\t`c` numeric DEFAULT (1, 2),\n\tPRIMARY KEY (id),\n\tUNIQUE (c)\n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(columns) != "[a b id c]" || rowid != 1 {
+		t.Errorf("Unexpected columns %q with rowid column %d", columns, rowid)
+	}
+	if len(defaults) != 3 || defaults[0] != "it's" || defaults[1] != nil || defaults[2] != nil {
+		t.Errorf("Unexpected defaults %#v", defaults)
+	}
+}
+
+func TestSqlDefault(t *testing.T) {
+	tests := map[string]interface{}{
+		"integer":                          nil,
+		"integer DEFAULT 0":                int64(0),
+		"integer NOT NULL DEFAULT -12":     int64(-12),
+		"integer DEFAULT 010":              int64(10),
+		"integer DEFAULT 0x10":             int64(16),
+		"real DEFAULT 1.5e3":               1500.0,
+		"numeric DEFAULT (TRUE)":           int64(1),
+		"numeric default false":            int64(0),
+		"text DEFAULT NULL":                nil,
+		"text DEFAULT '' NOT NULL":         "",
+		"text DEFAULT 'a, b'":              "a, b",
+		"blob DEFAULT x'0aff'":             "\x0a\xff",
+		"text DEFAULT CURRENT_TIMESTAMP":   nil,
+		"integer DEFAULT (1 + 2)":          nil,
+		"text DEFAULT ('a' || 'b')":        nil,
+		"text DEFAULT ('a')":               "a",
+		"text CHECK (x != 'DEFAULT 1')":    nil,
+		"integer DEFAULTS_TO_NOTHING":      nil,
+		"integer COLLATE NOCASE DEFAULT 7": int64(7),
+	}
+	for def, expected := range tests {
+		got := sqlDefault(def)
+		if b, ok := got.([]byte); ok {
+			got = string(b)
+		}
+		if got != expected {
+			t.Errorf("sqlDefault(%q) = %#v, expected %#v", def, got, expected)
+		}
+	}
+}
+
+func TestDBFileFresh(t *testing.T) {
+	d, err := OpenDBFile("testdata/x-ui-fresh.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if _, err := d.XraySettings(); !errors.Is(err, ErrXrayTemplateDefault) {
+		t.Errorf("Expected ErrXrayTemplateDefault, got %v", err)
+	}
+
+	// The first row was written before enable and reset were added with
+	// their defaults.
+	stats, err := d.ClientStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 client stats, got %d", len(stats))
+	}
+	if s := stats[0]; s.Email != "old" || !s.Enable || s.Reset != 30 || s.Up != KiB {
+		t.Errorf("Expected the column defaults, got %+v", s)
+	}
+	if s := stats[1]; s.Email != "new" || s.Enable || s.Reset != 0 {
+		t.Errorf("Unexpected client stat %+v", s)
+	}
+}
@@ -59,13 +59,19 @@ func (c *Client) Do(ctx context.Context, method, path string, in, out interface{
 }
 
 func (c *Client) DoRaw(ctx context.Context, method, baseurl, path, contentType string, body []byte) ([]byte, error) {
+	respBody, _, err := c.doRawWithHeader(ctx, method, baseurl, path, contentType, body)
+	return respBody, err
+}
+
+// doRawWithHeader is DoRaw that also returns the response headers.
+func (c *Client) doRawWithHeader(ctx context.Context, method, baseurl, path, contentType string, body []byte) ([]byte, http.Header, error) {
 	req, err := http.NewRequestWithContext(ctx, method, baseurl+path, bytes.NewBuffer(body))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	err = c.loginIfNoCookie(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	req.AddCookie(c.sessionCookie)
 	req.Header.Set("Content-Type", contentType)
@@ -74,13 +80,14 @@ func (c *Client) DoRaw(ctx context.Context, method, baseurl, path, contentType s
 	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http status %v", resp.StatusCode)
+		return nil, nil, fmt.Errorf("http status %v", resp.StatusCode)
 	}
-	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
+	return respBody, resp.Header, err
 }
 
 func (c *Client) DoForm(ctx context.Context, method, path string, form url.Values, out interface{}) error {
@@ -108,3 +115,32 @@ func (c *Client) DoForm(ctx context.Context, method, path string, form url.Value
 	}
 	return json.Unmarshal(body, out)
 }
+
+// doStream sends body as is and returns the response for the caller to
+// read and close, for transfers too large to buffer.
+func (c *Client) doStream(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	err = c.loginIfNoCookie(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.AddCookie(c.sessionCookie)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.host != "" {
+		req.Host = c.host
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("http status %v", resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,169 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakePanelURL = "http://panel.test"
+
+// fakePanel is an http.RoundTripper standing in for a panel. Requests to
+// /login always succeed, other paths are answered by their handler, or by
+// the handler of the longest matching prefix.
+type fakePanel struct {
+	t        *testing.T
+	mu       sync.Mutex
+	handlers map[string]func(*http.Request) (int, []byte)
+	prefixes map[string]func(*http.Request) (int, []byte)
+	calls    map[string]int
+	logins   int
+}
+
+func newFakePanel(t *testing.T) *fakePanel {
+	return &fakePanel{
+		t:        t,
+		handlers: map[string]func(*http.Request) (int, []byte){},
+		prefixes: map[string]func(*http.Request) (int, []byte){},
+		calls:    map[string]int{},
+	}
+}
+
+// client returns a Client talking to p.
+func (p *fakePanel) client() *Client {
+	return New(Config{
+		Url:      fakePanelURL,
+		Username: "admin",
+		Password: "admin",
+		Client:   &http.Client{Transport: p},
+	})
+}
+
+func (p *fakePanel) handle(path string, h func(*http.Request) (int, []byte)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[path] = h
+}
+
+// handlePrefix answers the paths starting with prefix that have no handler
+// of their own.
+func (p *fakePanel) handlePrefix(prefix string, h func(*http.Request) (int, []byte)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prefixes[prefix] = h
+}
+
+// handleObj answers path with a successful response holding obj.
+func (p *fakePanel) handleObj(path string, obj interface{}) {
+	p.handle(path, func(*http.Request) (int, []byte) {
+		return apiSuccess(obj)
+	})
+}
+
+func (p *fakePanel) callCount(path string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[path]
+}
+
+func (p *fakePanel) loginCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.logins
+}
+
+func (p *fakePanel) RoundTrip(r *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	path := r.URL.Path
+	p.calls[path]++
+	if path == "/login" {
+		p.logins++
+	}
+	h := p.handlers[path]
+	if h == nil {
+		longest := ""
+		for prefix, ph := range p.prefixes {
+			if strings.HasPrefix(path, prefix) && len(prefix) > len(longest) {
+				longest, h = prefix, ph
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	header := http.Header{}
+	var status int
+	var body []byte
+	switch {
+	case path == "/login":
+		cookie := &http.Cookie{Name: "3x-ui", Value: "session", Expires: time.Now().Add(24 * time.Hour)}
+		header.Add("Set-Cookie", cookie.String())
+		status, body = apiSuccess(nil)
+	case h != nil:
+		status, body = h(r)
+	default:
+		p.t.Errorf("Unexpected request %s %s", r.Method, path)
+		status = http.StatusNotFound
+	}
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func apiSuccess(obj interface{}) (int, []byte) {
+	b, _ := json.Marshal(map[string]interface{}{"success": true, "msg": "", "obj": obj})
+	return http.StatusOK, b
+}
+
+func apiFailure(msg string) (int, []byte) {
+	b, _ := json.Marshal(map[string]interface{}{"success": false, "msg": msg, "obj": nil})
+	return http.StatusOK, b
+}
+
+// handleStatuses answers /server/status with the Xray states in turn,
+// repeating the last one.
+func (p *fakePanel) handleStatuses(states ...XrayStatus) {
+	var mu sync.Mutex
+	p.handle("/server/status", func(*http.Request) (int, []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		x := states[0]
+		if len(states) > 1 {
+			states = states[1:]
+		}
+		return apiSuccess(ServerStatus{Xray: x})
+	})
+}
+
+// fastXrayPolls makes waiting for Xray poll every millisecond during t.
+func fastXrayPolls(t *testing.T) {
+	interval := xrayPollInterval
+	xrayPollInterval = time.Millisecond
+	t.Cleanup(func() { xrayPollInterval = interval })
+}
@@ -2,8 +2,10 @@ package client3xui
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 type GetX25519CertResponse struct {
@@ -17,6 +19,8 @@ type X25519Cert struct {
 	PublicKey  string `json:"publicKey"`
 }
 
+// GetX25519Cert returns a Reality key pair generated by the panel.
+// NewX25519Cert does the same locally.
 func (c *Client) GetX25519Cert(ctx context.Context) (*X25519Cert, error) {
 	resp := &GetX25519CertResponse{}
 
@@ -29,3 +33,89 @@ func (c *Client) GetX25519Cert(ctx context.Context) (*X25519Cert, error) {
 	}
 	return &resp.Obj, nil
 }
+
+// MLKEM768Key is an ML-KEM-768 key pair for VLESS encryption, as generated
+// by "xray mlkem768". Seed is the server's secret, Client its public key.
+type MLKEM768Key struct {
+	Seed   string `json:"seed"`
+	Client string `json:"client"`
+}
+
+// VlessEncAuth is a matching pair of VLESS decryption (server) and
+// encryption (client) settings.
+type VlessEncAuth struct {
+	Label      string `json:"label"`
+	Decryption string `json:"decryption"`
+	Encryption string `json:"encryption"`
+}
+
+// EchCert is an ECH key set for the server and its config list for clients.
+type EchCert struct {
+	EchServerKeys string `json:"echServerKeys"`
+	EchConfigList string `json:"echConfigList"`
+}
+
+// GetNewUUID returns a UUID generated by the panel. NewUUID does the same
+// locally.
+func (c *Client) GetNewUUID(ctx context.Context) (string, error) {
+	var obj struct {
+		UUID string `json:"uuid"`
+	}
+	if err := c.getGenerated(ctx, "/server/getNewUUID", nil, &obj); err != nil {
+		return "", err
+	}
+	return obj.UUID, nil
+}
+
+// GetNewMLKEM768 returns an ML-KEM-768 key pair generated by the panel.
+func (c *Client) GetNewMLKEM768(ctx context.Context) (*MLKEM768Key, error) {
+	key := &MLKEM768Key{}
+	if err := c.getGenerated(ctx, "/server/getNewmlkem768", nil, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetNewVlessEnc returns VLESS encryption settings generated by the panel,
+// one pair per authentication method.
+func (c *Client) GetNewVlessEnc(ctx context.Context) ([]VlessEncAuth, error) {
+	var obj struct {
+		Auths []VlessEncAuth `json:"auths"`
+	}
+	if err := c.getGenerated(ctx, "/server/getNewVlessEnc", nil, &obj); err != nil {
+		return nil, err
+	}
+	return obj.Auths, nil
+}
+
+// GetNewEchCert returns an ECH key set for serverName generated by the
+// panel.
+func (c *Client) GetNewEchCert(ctx context.Context, serverName string) (*EchCert, error) {
+	cert := &EchCert{}
+	if err := c.getGenerated(ctx, "/server/getNewEchCert", url.Values{"sni": {serverName}}, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// getGenerated calls one of the panel's generators and decodes its result
+// into out.
+func (c *Client) getGenerated(ctx context.Context, path string, form url.Values, out interface{}) error {
+	resp := &ApiResponse{}
+	var err error
+	if form != nil {
+		err = c.DoForm(ctx, http.MethodPost, path, form, resp)
+	} else {
+		err = c.Do(ctx, http.MethodPost, path, nil, resp)
+	}
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	if err := json.Unmarshal(resp.Obj, out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	return nil
+}
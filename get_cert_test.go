@@ -0,0 +1,139 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recordedGenerator serves the panel response recorded for path in
+// testdata/generators.json and returns its obj. check, if not nil, sees
+// each request.
+func recordedGenerator(t *testing.T, p *fakePanel, path string, check func(*http.Request)) json.RawMessage {
+	b, err := os.ReadFile("testdata/generators.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded map[string]json.RawMessage
+	if err := json.Unmarshal(b, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	var resp ApiResponse
+	if err := json.Unmarshal(recorded[path], &resp); err != nil {
+		t.Fatal(err)
+	}
+	p.handle(path, func(r *http.Request) (int, []byte) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if check != nil {
+			check(r)
+		}
+		return http.StatusOK, recorded[path]
+	})
+	return resp.Obj
+}
+
+func TestGetNewUUID(t *testing.T) {
+	p := newFakePanel(t)
+	obj := recordedGenerator(t, p, "/server/getNewUUID", nil)
+	var want struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(obj, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := p.client().GetNewUUID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" || id != want.UUID {
+		t.Errorf("Expected %q, got %q", want.UUID, id)
+	}
+}
+
+func TestGetNewMLKEM768(t *testing.T) {
+	p := newFakePanel(t)
+	obj := recordedGenerator(t, p, "/server/getNewmlkem768", nil)
+	var want MLKEM768Key
+	if err := json.Unmarshal(obj, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := p.client().GetNewMLKEM768(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Seed == "" || key.Client == "" || *key != want {
+		t.Errorf("Expected %+v, got %+v", want, key)
+	}
+}
+
+func TestGetNewVlessEnc(t *testing.T) {
+	p := newFakePanel(t)
+	obj := recordedGenerator(t, p, "/server/getNewVlessEnc", nil)
+	var want struct {
+		Auths []VlessEncAuth `json:"auths"`
+	}
+	if err := json.Unmarshal(obj, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	auths, err := p.client().GetNewVlessEnc(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auths) != 2 || !slices.Equal(auths, want.Auths) {
+		t.Fatalf("Expected %+v, got %+v", want.Auths, auths)
+	}
+	for _, auth := range auths {
+		if !strings.HasPrefix(auth.Decryption, "mlkem768x25519plus.") || !strings.HasPrefix(auth.Encryption, "mlkem768x25519plus.") {
+			t.Errorf("Unexpected pair %+v", auth)
+		}
+	}
+}
+
+func TestGetNewEchCert(t *testing.T) {
+	p := newFakePanel(t)
+	obj := recordedGenerator(t, p, "/server/getNewEchCert", func(r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("Expected a form, got %q", ct)
+		}
+		if sni := r.FormValue("sni"); sni != "example.com" {
+			t.Errorf("Expected sni example.com, got %q", sni)
+		}
+	})
+	var want EchCert
+	if err := json.Unmarshal(obj, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := p.client().GetNewEchCert(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.EchServerKeys == "" || cert.EchConfigList == "" || *cert != want {
+		t.Errorf("Expected %+v, got %+v", want, cert)
+	}
+}
@@ -15,12 +15,12 @@ type GetInboundsResponse struct {
 
 type Inbound struct {
 	ID             int          `json:"id"`
-	Up             int          `json:"up"`
-	Down           int          `json:"down"`
-	Total          int          `json:"total"`
+	Up             ByteSize     `json:"up"`
+	Down           ByteSize     `json:"down"`
+	Total          ByteSize     `json:"total"`
 	Remark         string       `json:"remark"`
 	Enable         bool         `json:"enable"`
-	ExpiryTime     int          `json:"expiryTime"`
+	ExpiryTime     Expiry       `json:"expiryTime"`
 	ClientStats    []ClientStat `json:"clientStats"`
 	Listen         string       `json:"listen"`
 	Port           int          `json:"port"`
@@ -43,6 +43,36 @@ func (i Inbound) GetVmessSettings() (VmessSettings, error) {
 	return settings, err
 }
 
+// Clients returns the clients of the inbound regardless of its protocol.
+func (i Inbound) Clients() ([]InboundClient, error) {
+	var settings struct {
+		Clients []InboundClient `json:"clients"`
+	}
+	if i.Settings == "" {
+		return nil, nil
+	}
+	err := json.Unmarshal([]byte(i.Settings), &settings)
+	return settings.Clients, err
+}
+
+func (i Inbound) GetTrojanSettings() (TrojanSettings, error) {
+	var settings TrojanSettings
+	err := json.Unmarshal([]byte(i.Settings), &settings)
+	return settings, err
+}
+
+func (i Inbound) GetShadowsocksSettings() (ShadowsocksSettings, error) {
+	var settings ShadowsocksSettings
+	err := json.Unmarshal([]byte(i.Settings), &settings)
+	return settings, err
+}
+
+func (i Inbound) GetStreamSettings() (InboundStreamSettings, error) {
+	var settings InboundStreamSettings
+	err := json.Unmarshal([]byte(i.StreamSettings), &settings)
+	return settings, err
+}
+
 func (i Inbound) GetTcpStreamSettings() (TcpStreamSettings, error) {
 	var settings TcpStreamSettings
 	err := json.Unmarshal([]byte(i.StreamSettings), &settings)
@@ -62,15 +92,28 @@ func (i Inbound) GetSniffingSettings() (SniffingSettings, error) {
 }
 
 type ClientStat struct {
-	ID         int    `json:"id,omitempty"`
-	InboundID  int    `json:"inboundId,omitempty"`
-	Enable     bool   `json:"enable,omitempty"`
-	Email      string `json:"email,omitempty"`
-	Up         int    `json:"up,omitempty"`
-	Down       int    `json:"down,omitempty"`
-	ExpiryTime int    `json:"expiryTime,omitempty"`
-	Total      int    `json:"total,omitempty"`
-	Reset      int    `json:"reset,omitempty"`
+	ID         int      `json:"id,omitempty"`
+	InboundID  int      `json:"inboundId,omitempty"`
+	Enable     bool     `json:"enable,omitempty"`
+	Email      string   `json:"email,omitempty"`
+	Up         ByteSize `json:"up,omitempty"`
+	Down       ByteSize `json:"down,omitempty"`
+	ExpiryTime Expiry   `json:"expiryTime,omitempty"`
+	Total      ByteSize `json:"total,omitempty"`
+	Reset      int      `json:"reset,omitempty"`
+}
+
+// Used returns the traffic the client has consumed so far.
+func (s ClientStat) Used() ByteSize {
+	return s.Up + s.Down
+}
+
+// Remaining returns the quota left, or zero if the quota is unlimited or used up.
+func (s ClientStat) Remaining() ByteSize {
+	if s.Total <= 0 || s.Used() >= s.Total {
+		return 0
+	}
+	return s.Total - s.Used()
 }
 
 func (c *Client) GetInbounds(ctx context.Context) (*GetInboundsResponse, error) {
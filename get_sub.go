@@ -0,0 +1,217 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubscriptionUserinfo is the traffic and expiry information of the
+// Subscription-Userinfo header.
+type SubscriptionUserinfo struct {
+	Upload   ByteSize
+	Download ByteSize
+	// Zero means unlimited.
+	Total ByteSize
+	// Zero time means the subscription never expires.
+	Expire time.Time
+}
+
+// SkippedLink is a subscription entry that could not be parsed.
+type SkippedLink struct {
+	Link string
+	Err  error
+}
+
+// Subscription is a decoded base64 subscription.
+type Subscription struct {
+	Links []string
+	// Outbounds parsed from Links, in the same order. Links that failed to
+	// parse are listed in Skipped instead.
+	Outbounds []XrayOutbound
+	Skipped   []SkippedLink
+	// Nil if the panel didn't send the Subscription-Userinfo header.
+	Userinfo *SubscriptionUserinfo
+	// From the Profile-Update-Interval header, zero if not sent.
+	UpdateInterval time.Duration
+	// From the Profile-Title header.
+	Title string
+}
+
+// SubscriptionEncoding is how the panel encodes subscription bodies.
+type SubscriptionEncoding int
+
+const (
+	// SubEncodingAuto detects the encoding from the body.
+	SubEncodingAuto SubscriptionEncoding = iota
+	// SubEncodingBase64 is used when PanelSettings.SubEncrypt is on.
+	SubEncodingBase64
+	// SubEncodingPlain is a newline separated list of links, used when
+	// PanelSettings.SubEncrypt is off.
+	SubEncodingPlain
+)
+
+// SubscriptionEncoding returns the encoding of the panel's subscription
+// bodies.
+func (s PanelSettings) SubscriptionEncoding() SubscriptionEncoding {
+	if s.SubEncrypt {
+		return SubEncodingBase64
+	}
+	return SubEncodingPlain
+}
+
+// GetSub fetches the subscription of subID from the subscription server and
+// parses its links and headers. Without Config.SubUrl the subscription URL
+// and encoding are discovered from the panel settings, otherwise the
+// encoding is detected from the body.
+func (c *Client) GetSub(ctx context.Context, subID string) (*Subscription, error) {
+	base, _, err := c.subscriptionBases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, header, err := c.doRawWithHeader(ctx, http.MethodGet, base, url.PathEscape(subID), "text/plain", nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEncodedSubscription(body, header, c.subEncoding)
+}
+
+// ParseSubscription decodes a subscription body into share links and
+// outbounds, detecting whether it is base64 encoded. header may be nil.
+func ParseSubscription(body []byte, header http.Header) (*Subscription, error) {
+	return ParseEncodedSubscription(body, header, SubEncodingAuto)
+}
+
+// ParseEncodedSubscription is ParseSubscription for a known encoding.
+func ParseEncodedSubscription(body []byte, header http.Header, encoding SubscriptionEncoding) (*Subscription, error) {
+	links, err := DecodeSubscription(body, encoding)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{}
+	for _, line := range links {
+		sub.Links = append(sub.Links, line)
+		outbound, err := ParseShareLink(line)
+		if err != nil {
+			sub.Skipped = append(sub.Skipped, SkippedLink{Link: line, Err: err})
+			continue
+		}
+		sub.Outbounds = append(sub.Outbounds, *outbound)
+	}
+
+	if header == nil {
+		return sub, nil
+	}
+	if v := header.Get("Subscription-Userinfo"); v != "" {
+		if sub.Userinfo, err = ParseSubscriptionUserinfo(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := header.Get("Profile-Update-Interval"); v != "" {
+		hours, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Profile-Update-Interval %q", v)
+		}
+		sub.UpdateInterval = time.Duration(hours) * time.Hour
+	}
+	if v := header.Get("Profile-Title"); v != "" {
+		if sub.Title, err = decodeProfileTitle(v); err != nil {
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+// DecodeSubscription returns the links of a subscription body. With
+// SubEncodingAuto a body containing "://" is taken as plain, since that
+// can't appear in base64.
+func DecodeSubscription(body []byte, encoding SubscriptionEncoding) ([]string, error) {
+	text := strings.TrimSpace(string(body))
+	if encoding == SubEncodingAuto {
+		encoding = SubEncodingBase64
+		if strings.Contains(text, "://") {
+			encoding = SubEncodingPlain
+		}
+	}
+	if encoding == SubEncodingBase64 {
+		decoded, err := decodeBase64(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 subscription body: %w", err)
+		}
+		text = string(decoded)
+	}
+	var links []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			links = append(links, line)
+		}
+	}
+	return links, nil
+}
+
+// ParseSubscriptionUserinfo parses a Subscription-Userinfo header value such
+// as "upload=1; download=2; total=3; expire=1700000000". Unknown keys are
+// ignored.
+func ParseSubscriptionUserinfo(v string) (*SubscriptionUserinfo, error) {
+	info := &SubscriptionUserinfo{}
+	for _, part := range strings.Split(v, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid Subscription-Userinfo entry %q", part)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Subscription-Userinfo entry %q", part)
+		}
+		switch strings.TrimSpace(key) {
+		case "upload":
+			info.Upload = ByteSize(n)
+		case "download":
+			info.Download = ByteSize(n)
+		case "total":
+			info.Total = ByteSize(n)
+		case "expire":
+			if n > 0 {
+				info.Expire = time.Unix(n, 0)
+			}
+		}
+	}
+	return info, nil
+}
+
+// decodeProfileTitle handles titles sent as "base64:<encoded>".
+func decodeProfileTitle(v string) (string, error) {
+	encoded, ok := strings.CutPrefix(v, "base64:")
+	if !ok {
+		return v, nil
+	}
+	title, err := decodeBase64(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid Profile-Title: %w", err)
+	}
+	return string(title), nil
+}
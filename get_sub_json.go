@@ -17,10 +17,133 @@
 package client3xui
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 )
 
 func (c *Client) GetSubJson(ctx context.Context, subID string) ([]byte, error) {
-	return c.DoRaw(ctx, http.MethodGet, c.subUrl, "/json/"+subID, "application/json", nil)
+	_, base, err := c.subscriptionBases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.DoRaw(ctx, http.MethodGet, base, url.PathEscape(subID), "application/json", nil)
+}
+
+// SubJsonConfig is one complete Xray client config of the JSON subscription,
+// built by the panel from the client's inbound and the SubJsonFragment,
+// SubJsonMux and SubJsonRules settings.
+type SubJsonConfig struct {
+	XraySettings
+	Remarks string                 `json:"remarks,omitempty"`
+	DNS     map[string]interface{} `json:"dns,omitempty"`
+	// Top-level keys not covered by the fields above. They are written back
+	// by MarshalJSON so a config survives a round trip.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// subJsonConfigFields lets the (un)marshalers reach the plain fields without
+// recursing into themselves.
+type subJsonConfigFields struct {
+	XraySettings
+	Remarks string                 `json:"remarks,omitempty"`
+	DNS     map[string]interface{} `json:"dns,omitempty"`
+}
+
+func (s *SubJsonConfig) UnmarshalJSON(data []byte) error {
+	var fields subJsonConfigFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, key := range []string{"log", "api", "inbounds", "outbounds", "policy", "routing", "stats", "remarks", "dns"} {
+		delete(raw, key)
+	}
+	*s = SubJsonConfig{XraySettings: fields.XraySettings, Remarks: fields.Remarks, DNS: fields.DNS}
+	if len(raw) > 0 {
+		s.Extra = raw
+	}
+	return nil
+}
+
+func (s SubJsonConfig) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(subJsonConfigFields{XraySettings: s.XraySettings, Remarks: s.Remarks, DNS: s.DNS})
+	if err != nil || len(s.Extra) == 0 {
+		return data, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range s.Extra {
+		if _, ok := merged[k]; !ok {
+			merged[k] = v
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// proxyProtocols are the outbound protocols that carry user traffic to a server.
+var proxyProtocols = map[string]bool{
+	"vless":       true,
+	"vmess":       true,
+	"trojan":      true,
+	"shadowsocks": true,
+	"socks":       true,
+	"http":        true,
+	"wireguard":   true,
+	"hysteria":    true,
+}
+
+// ProxyOutbound returns the outbound that connects to the server: the one
+// tagged "proxy" as the panel writes it, or else the first outbound with a
+// proxy protocol.
+func (s SubJsonConfig) ProxyOutbound() (*XrayOutbound, error) {
+	for i := range s.Outbounds {
+		if s.Outbounds[i].Tag == "proxy" {
+			return &s.Outbounds[i], nil
+		}
+	}
+	for i := range s.Outbounds {
+		if proxyProtocols[s.Outbounds[i].Protocol] {
+			return &s.Outbounds[i], nil
+		}
+	}
+	return nil, fmt.Errorf("config %q has no proxy outbound", s.Remarks)
+}
+
+// ParseSubJson decodes a JSON subscription. The panel sends a single config
+// as an object and several as an array; both yield a slice.
+func ParseSubJson(data []byte) ([]SubJsonConfig, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty JSON subscription")
+	}
+	if data[0] == '{' {
+		var config SubJsonConfig
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("invalid JSON subscription: %w", err)
+		}
+		return []SubJsonConfig{config}, nil
+	}
+	var configs []SubJsonConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid JSON subscription: %w", err)
+	}
+	return configs, nil
+}
+
+// GetSubJsonConfigs fetches the JSON subscription of subID and decodes it.
+func (c *Client) GetSubJsonConfigs(ctx context.Context, subID string) ([]SubJsonConfig, error) {
+	data, err := c.GetSubJson(ctx, subID)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSubJson(data)
 }
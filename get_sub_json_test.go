@@ -0,0 +1,79 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubJson(t *testing.T) {
+	data, err := os.ReadFile("testdata/sub_json.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := ParseSubJson(data)
+	if err != nil {
+		t.Fatalf("ParseSubJson() error = %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(configs))
+	}
+	if configs[0].Remarks != "de-alice" || configs[0].Routing.Rules[2].Port != float64(53) {
+		t.Errorf("Unexpected config %+v", configs[0])
+	}
+	if _, ok := configs[0].Extra["observatory"]; !ok {
+		t.Error("Expected unknown key 'observatory' to be kept")
+	}
+
+	proxy, err := configs[0].ProxyOutbound()
+	if err != nil {
+		t.Fatalf("ProxyOutbound() error = %v", err)
+	}
+	if proxy.Protocol != "vless" || proxy.Mux["concurrency"] != float64(8) {
+		t.Errorf("Unexpected proxy outbound %+v", proxy)
+	}
+
+	// Re-serializing the proxy outbound must not lose anything.
+	var original []struct {
+		Outbounds []interface{} `json:"outbounds"`
+	}
+	if err := json.Unmarshal(data, &original); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(proxy)
+	if err != nil {
+		t.Fatalf("Failed to marshal proxy outbound: %v", err)
+	}
+	var roundTrip interface{}
+	if err := json.Unmarshal(out, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(original[0].Outbounds[0], roundTrip) {
+		t.Errorf("Round trip changed the proxy outbound:\n%s", out)
+	}
+
+	single, err := ParseSubJson([]byte(`{"remarks":"one","outbounds":[{"protocol":"freedom","tag":"direct"},{"protocol":"trojan","tag":"t"}]}`))
+	if err != nil {
+		t.Fatalf("ParseSubJson() error = %v", err)
+	}
+	if proxy, err := single[0].ProxyOutbound(); err != nil || proxy.Tag != "t" {
+		t.Errorf("Expected trojan outbound as proxy, got %+v, %v", proxy, err)
+	}
+}
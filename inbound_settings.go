@@ -29,10 +29,10 @@ type TcpSettings struct {
 }
 
 type TcpStreamSettings struct {
-	Network       string      `json:"network"`
-	Security      string      `json:"security"`
-	ExternalProxy []string    `json:"externalProxy"`
-	TcpSettings   TcpSettings `json:"tcpSettings"`
+	Network       string          `json:"network"`
+	Security      string          `json:"security"`
+	ExternalProxy []ExternalProxy `json:"externalProxy"`
+	TcpSettings   TcpSettings     `json:"tcpSettings"`
 	// RealitySettings is only included in JSON if Security is "reality"
 	RealitySettings *RealitySettings `json:"realitySettings,omitempty"`
 }
@@ -84,6 +84,18 @@ type VmessSettings struct {
 	Clients []InboundClient `json:"clients"`
 }
 
+type TrojanSettings struct {
+	Clients   []InboundClient   `json:"clients"`
+	Fallbacks []FallbackOptions `json:"fallbacks"`
+}
+
+type ShadowsocksSettings struct {
+	Method   string          `json:"method"`
+	Password string          `json:"password"`
+	Network  string          `json:"network"`
+	Clients  []InboundClient `json:"clients"`
+}
+
 type FallbackOptions struct {
 	Name string `json:"name"`
 	Alpn string `json:"alpn"`
@@ -95,14 +107,38 @@ type FallbackOptions struct {
 type InboundClient struct {
 	Email      string `json:"email"`
 	Enable     bool   `json:"enable"`
-	ExpiryTime int    `json:"expiryTime"`
+	ExpiryTime Expiry `json:"expiryTime"`
 	Flow       string `json:"flow,omitempty"`
 	ID         string `json:"id"`
+	Password   string `json:"password,omitempty"`
+	Security   string `json:"security,omitempty"`
 	LimitIp    int    `json:"limitIp"`
 	Reset      int    `json:"reset"`
 	SubId      string `json:"subId,omitempty"`
 	// TgId       string `json:"tgId,omitempty"`
-	TotalGB int `json:"totalGB"`
+	// Traffic quota in bytes, zero means unlimited.
+	TotalGB ByteSize `json:"totalGB"`
+	Comment string   `json:"comment,omitempty"`
+	// Labels decoded from Comment, nil if it holds free text. Use SetLabels
+	// to change them.
+	Labels Labels `json:"-"`
+}
+
+func (c *InboundClient) UnmarshalJSON(data []byte) error {
+	type Alias InboundClient
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	c.Labels = nil
+	if labels, err := ParseLabels(c.Comment); err == nil {
+		c.Labels = labels
+	}
+	return nil
 }
 
 type Fallback struct {
@@ -115,6 +151,98 @@ type InboundBaseSettings struct {
 
 type HeaderSetting struct {
 	Type string `json:"type"`
+	// Request is only set for the "http" header type.
+	Request *HeaderRequest `json:"request,omitempty"`
+}
+
+type HeaderRequest struct {
+	Version string              `json:"version,omitempty"`
+	Method  string              `json:"method,omitempty"`
+	Path    []string            `json:"path,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+}
+
+// ExternalProxy is a public address clients should use instead of the
+// server's own, e.g. a CDN or a port forward.
+type ExternalProxy struct {
+	// "same", "tls" or "none"
+	ForceTls string `json:"forceTls"`
+	Dest     string `json:"dest"`
+	Port     int    `json:"port"`
+	Remark   string `json:"remark"`
+}
+
+type KcpSettings struct {
+	Mtu              int           `json:"mtu"`
+	Tti              int           `json:"tti"`
+	UplinkCapacity   int           `json:"uplinkCapacity"`
+	DownlinkCapacity int           `json:"downlinkCapacity"`
+	Congestion       bool          `json:"congestion"`
+	ReadBufferSize   int           `json:"readBufferSize"`
+	WriteBufferSize  int           `json:"writeBufferSize"`
+	Header           HeaderSetting `json:"header"`
+	Seed             string        `json:"seed"`
+}
+
+type WsSettings struct {
+	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+	Path                string            `json:"path"`
+	Host                string            `json:"host"`
+	Headers             map[string]string `json:"headers"`
+}
+
+type GrpcSettings struct {
+	ServiceName string `json:"serviceName"`
+	Authority   string `json:"authority"`
+	MultiMode   bool   `json:"multiMode"`
+}
+
+type HttpUpgradeSettings struct {
+	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
+	Path                string            `json:"path"`
+	Host                string            `json:"host"`
+	Headers             map[string]string `json:"headers"`
+}
+
+type XhttpSettings struct {
+	Path    string            `json:"path"`
+	Host    string            `json:"host"`
+	Headers map[string]string `json:"headers"`
+	Mode    string            `json:"mode"`
+}
+
+type TlsClientSettings struct {
+	AllowInsecure bool   `json:"allowInsecure"`
+	Fingerprint   string `json:"fingerprint"`
+	EchConfigList string `json:"echConfigList,omitempty"`
+}
+
+// TlsSettings omits certificates, they are never needed on the client side.
+type TlsSettings struct {
+	ServerName       string            `json:"serverName"`
+	MinVersion       string            `json:"minVersion"`
+	MaxVersion       string            `json:"maxVersion"`
+	CipherSuites     string            `json:"cipherSuites"`
+	RejectUnknownSni bool              `json:"rejectUnknownSni"`
+	Alpn             []string          `json:"alpn"`
+	Settings         TlsClientSettings `json:"settings"`
+}
+
+// InboundStreamSettings covers the stream settings of every transport and
+// security the panel supports, for reading. Only the section matching
+// Network, and Security, is set.
+type InboundStreamSettings struct {
+	Network             string               `json:"network"`
+	Security            string               `json:"security"`
+	ExternalProxy       []ExternalProxy      `json:"externalProxy"`
+	TcpSettings         *TcpSettings         `json:"tcpSettings,omitempty"`
+	KcpSettings         *KcpSettings         `json:"kcpSettings,omitempty"`
+	WsSettings          *WsSettings          `json:"wsSettings,omitempty"`
+	GrpcSettings        *GrpcSettings        `json:"grpcSettings,omitempty"`
+	HttpUpgradeSettings *HttpUpgradeSettings `json:"httpupgradeSettings,omitempty"`
+	XhttpSettings       *XhttpSettings       `json:"xhttpSettings,omitempty"`
+	TlsSettings         *TlsSettings         `json:"tlsSettings,omitempty"`
+	RealitySettings     *RealitySettings     `json:"realitySettings,omitempty"`
 }
 
 type SniffingSettings struct {
@@ -131,8 +259,8 @@ type QuicSettings struct {
 }
 
 type QuicStreamSettings struct {
-	Network       string       `json:"network"`
-	Security      string       `json:"security"`
-	ExternalProxy []string     `json:"externalProxy"`
-	QuicSettings  QuicSettings `json:"quicSettings"`
+	Network       string          `json:"network"`
+	Security      string          `json:"security"`
+	ExternalProxy []ExternalProxy `json:"externalProxy"`
+	QuicSettings  QuicSettings    `json:"quicSettings"`
 }
@@ -0,0 +1,142 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand/v2"
+	"strings"
+)
+
+// NewX25519Cert generates a Reality key pair locally, in the format of
+// GetX25519Cert and "xray x25519": unpadded base64url.
+func NewX25519Cert() (*X25519Cert, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	// Clamped like Xray does, so the stored private key is the scalar.
+	key[0] &= 248
+	key[31] &= 127
+	key[31] |= 64
+	private := base64.RawURLEncoding.EncodeToString(key)
+	public, err := RealityPublicKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Cert{PrivateKey: private, PublicKey: public}, nil
+}
+
+// RealityPublicKey derives the public key of a Reality private key. Padded
+// and standard base64 are accepted, the result is unpadded base64url.
+func RealityPublicKey(privateKey string) (string, error) {
+	key, err := decodeX25519Key(privateKey)
+	if err != nil {
+		return "", err
+	}
+	private, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(private.PublicKey().Bytes()), nil
+}
+
+// CheckPublicKey reports an error if Settings.PublicKey doesn't belong to
+// PrivateKey, which leaves clients unable to connect.
+func (s RealitySettings) CheckPublicKey() error {
+	public, err := RealityPublicKey(s.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("invalid reality private key: %w", err)
+	}
+	key, err := decodeX25519Key(s.Settings.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid reality public key: %w", err)
+	}
+	if base64.RawURLEncoding.EncodeToString(key) != public {
+		return fmt.Errorf("reality public key %s does not match the private key, expected %s", s.Settings.PublicKey, public)
+	}
+	return nil
+}
+
+func decodeX25519Key(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key is %d bytes, expected 32", len(key))
+	}
+	return key, nil
+}
+
+// NewShortID returns a random Reality short ID of length hex digits, which
+// must be even and at most 16.
+func NewShortID(length int) (string, error) {
+	if length < 0 || length > 16 || length%2 != 0 {
+		return "", fmt.Errorf("invalid short ID length %d", length)
+	}
+	b := make([]byte, length/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewShortIDs returns short IDs like the panel generates for a new Reality
+// inbound: one of each even length from 2 to 16, in random order.
+func NewShortIDs() ([]string, error) {
+	lengths := []int{2, 4, 6, 8, 10, 12, 14, 16}
+	mathrand.Shuffle(len(lengths), func(i, j int) {
+		lengths[i], lengths[j] = lengths[j], lengths[i]
+	})
+	ids := make([]string, len(lengths))
+	for i, length := range lengths {
+		id, err := NewShortID(length)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// NewShadowsocksKey returns a random base64 key for method, sized for
+// Shadowsocks-2022 methods and 32 bytes for others, like the panel.
+func NewShadowsocksKey(method string) (string, error) {
+	size := ss2022KeySize(method)
+	if size == 0 {
+		size = 32
+	}
+	return randomBase64Key(size)
+}
+
+// NewUUID returns a random (version 4) UUID.
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
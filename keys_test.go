@@ -0,0 +1,117 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestRealityPublicKey(t *testing.T) {
+	// RFC 7748 section 6.1.
+	private, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	public, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	expected := base64.RawURLEncoding.EncodeToString(public)
+
+	for _, key := range []string{base64.RawURLEncoding.EncodeToString(private), base64.StdEncoding.EncodeToString(private)} {
+		got, err := RealityPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("RealityPublicKey(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+	if _, err := RealityPublicKey("c2hvcnQ"); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
+
+func TestNewX25519Cert(t *testing.T) {
+	cert, err := NewX25519Cert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := RealitySettings{PrivateKey: cert.PrivateKey, Settings: RealityPublicSettings{PublicKey: cert.PublicKey}}
+	if err := settings.CheckPublicKey(); err != nil {
+		t.Error(err)
+	}
+	key, _ := base64.RawURLEncoding.DecodeString(cert.PrivateKey)
+	if key[0]&7 != 0 || key[31]&0xc0 != 0x40 {
+		t.Errorf("Expected a clamped private key, got %x", key)
+	}
+
+	other, err := NewX25519Cert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Settings.PublicKey = other.PublicKey
+	if err := settings.CheckPublicKey(); err == nil {
+		t.Error("Expected an error for a mismatched public key")
+	}
+}
+
+func TestNewShortIDs(t *testing.T) {
+	ids, err := NewShortIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lengths []int
+	for _, id := range ids {
+		if _, err := hex.DecodeString(id); err != nil {
+			t.Errorf("Expected a hex short ID, got %q", id)
+		}
+		lengths = append(lengths, len(id))
+	}
+	slices.Sort(lengths)
+	if !slices.Equal(lengths, []int{2, 4, 6, 8, 10, 12, 14, 16}) {
+		t.Errorf("Unexpected short ID lengths %v", lengths)
+	}
+	if _, err := NewShortID(3); err == nil {
+		t.Error("Expected an error for an odd length")
+	}
+}
+
+func TestNewShadowsocksKey(t *testing.T) {
+	tests := map[string]int{
+		"2022-blake3-aes-128-gcm":       16,
+		"2022-blake3-chacha20-poly1305": 32,
+		"chacha20-ietf-poly1305":        32,
+	}
+	for method, size := range tests {
+		key, err := NewShadowsocksKey(method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != size {
+			t.Errorf("Expected a %d byte base64 key for %s, got %q", size, method, key)
+		}
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	id, err := NewUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("Expected a version 4 UUID, got %q", id)
+	}
+}
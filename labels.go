@@ -0,0 +1,264 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels is a small key/value map stored in a client's comment as
+// "key=value; key=value" with keys sorted, so the panel UI stays readable.
+// Values escape '%', ';', '=' and control characters as %XX.
+type Labels map[string]string
+
+// ParseLabels decodes a comment written by Labels.String. An empty comment
+// yields empty labels, free-text comments return an error.
+func ParseLabels(comment string) (Labels, error) {
+	labels := Labels{}
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(comment, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid label %q: missing '='", pair)
+		}
+		if !validLabelKey(key) {
+			return nil, fmt.Errorf("invalid label key %q", key)
+		}
+		unescaped, err := unescapeLabelValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %q: %w", pair, err)
+		}
+		labels[key] = unescaped
+	}
+	return labels, nil
+}
+
+// String encodes the labels for a client comment. Keys are sorted so equal
+// maps always produce the same comment.
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+escapeLabelValue(l[k]))
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// Validate checks that every key can be encoded.
+func (l Labels) Validate() error {
+	for k := range l {
+		if !validLabelKey(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+	}
+	return nil
+}
+
+// Keys may contain letters, digits and '.', '_', '-', '/'.
+func validLabelKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func escapeLabelValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch == '%' || ch == ';' || ch == '=' || ch < 0x20 || ch == 0x7f ||
+			(ch == ' ' && (i == 0 || i == len(value)-1)) {
+			fmt.Fprintf(&b, "%%%02X", ch)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+func unescapeLabelValue(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+2 >= len(value) {
+			return "", fmt.Errorf("truncated escape in %q", value)
+		}
+		ch, err := strconv.ParseUint(value[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		b.WriteByte(byte(ch))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// SetLabels stores labels in the client's comment, replacing its content.
+func (c *InboundClient) SetLabels(labels Labels) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+	c.Comment = labels.String()
+	c.Labels = labels
+	return nil
+}
+
+// SetLabels stores labels in the client's comment, replacing its content.
+func (c *XrayClient) SetLabels(labels Labels) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+	c.Comment = labels.String()
+	return nil
+}
+
+type labelRequirement struct {
+	key, value string
+	// "=", "!=", "exists" or "!exists"
+	op string
+}
+
+// LabelSelector matches labels against a list of requirements, all of which
+// must hold.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// ParseLabelSelector parses a comma separated selector such as
+// "plan=pro,region!=eu,reseller,!trial". A bare key requires the label to be
+// present, "!key" requires it to be absent.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var s LabelSelector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var req labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			k, v, _ := strings.Cut(part, "!=")
+			req = labelRequirement{key: strings.TrimSpace(k), value: strings.TrimSpace(v), op: "!="}
+		case strings.Contains(part, "="):
+			k, v, _ := strings.Cut(part, "=")
+			req = labelRequirement{key: strings.TrimSpace(k), value: strings.TrimSpace(v), op: "="}
+		case strings.HasPrefix(part, "!"):
+			req = labelRequirement{key: strings.TrimSpace(part[1:]), op: "!exists"}
+		default:
+			req = labelRequirement{key: part, op: "exists"}
+		}
+		if !validLabelKey(req.key) {
+			return LabelSelector{}, fmt.Errorf("invalid label selector %q: bad key %q", part, req.key)
+		}
+		s.requirements = append(s.requirements, req)
+	}
+	return s, nil
+}
+
+// Matches reports whether labels satisfy every requirement of the selector.
+func (s LabelSelector) Matches(labels Labels) bool {
+	for _, req := range s.requirements {
+		value, ok := labels[req.key]
+		switch req.op {
+		case "=":
+			if !ok || value != req.value {
+				return false
+			}
+		case "!=":
+			if ok && value == req.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FilterClientsByLabels returns the clients whose labels match the selector.
+// Clients with free-text comments never match.
+func FilterClientsByLabels(clients []InboundClient, selector LabelSelector) []InboundClient {
+	var out []InboundClient
+	for _, client := range clients {
+		if client.Labels != nil && selector.Matches(client.Labels) {
+			out = append(out, client)
+		}
+	}
+	return out
+}
+
+// LabeledClient is a client found by FindClientsByLabels.
+type LabeledClient struct {
+	InboundID int
+	Client    InboundClient
+}
+
+// FindClientsByLabels searches the clients of every inbound for labels
+// matching the selector, e.g. "plan=pro,region=eu".
+func (c *Client) FindClientsByLabels(ctx context.Context, selector string) ([]LabeledClient, error) {
+	sel, err := ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []LabeledClient
+	for _, inbound := range inbounds.Obj {
+		clients, err := inbound.Clients()
+		if err != nil {
+			return nil, fmt.Errorf("inbound %d: %w", inbound.ID, err)
+		}
+		for _, client := range FilterClientsByLabels(clients, sel) {
+			found = append(found, LabeledClient{InboundID: inbound.ID, Client: client})
+		}
+	}
+	return found, nil
+}
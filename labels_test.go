@@ -0,0 +1,119 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLabelsRoundTrip(t *testing.T) {
+	labels := Labels{
+		"region":   "eu",
+		"customer": "42",
+		"note":     " a;b=c%d ",
+	}
+	comment := labels.String()
+	if comment != "customer=42; note=%20a%3Bb%3Dc%25d%20; region=eu" {
+		t.Errorf("Unexpected comment %q", comment)
+	}
+	parsed, err := ParseLabels(comment)
+	if err != nil {
+		t.Fatalf("ParseLabels() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, labels) {
+		t.Errorf("Expected %v, got %v", labels, parsed)
+	}
+
+	if _, err := ParseLabels("just a note"); err == nil {
+		t.Error("Expected error for free-text comment")
+	}
+}
+
+func TestInboundClientLabelsDecoding(t *testing.T) {
+	var settings VlessSettings
+	err := json.Unmarshal([]byte(`{"clients":[
+		{"email":"a","comment":"plan=pro; region=eu"},
+		{"email":"b","comment":"VIP customer"},
+		{"email":"c","comment":"plan=basic; region=eu"}
+	]}`), &settings)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal settings: %v", err)
+	}
+	if settings.Clients[0].Labels["plan"] != "pro" {
+		t.Errorf("Expected plan 'pro', got %v", settings.Clients[0].Labels)
+	}
+	if settings.Clients[1].Labels != nil {
+		t.Errorf("Expected no labels for free-text comment, got %v", settings.Clients[1].Labels)
+	}
+
+	sel, err := ParseLabelSelector("region=eu,plan!=basic")
+	if err != nil {
+		t.Fatalf("ParseLabelSelector() error = %v", err)
+	}
+	matched := FilterClientsByLabels(settings.Clients, sel)
+	if len(matched) != 1 || matched[0].Email != "a" {
+		t.Errorf("Expected only client 'a' to match, got %v", matched)
+	}
+
+	client := settings.Clients[2]
+	if err := client.SetLabels(Labels{"plan": "pro"}); err != nil {
+		t.Fatalf("SetLabels() error = %v", err)
+	}
+	data, err := json.Marshal(client)
+	if err != nil {
+		t.Fatalf("Failed to marshal client: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal client: %v", err)
+	}
+	if raw["comment"] != "plan=pro" {
+		t.Errorf("Expected comment 'plan=pro', got %v", raw["comment"])
+	}
+	if _, ok := raw["Labels"]; ok {
+		t.Error("Labels must not be marshaled")
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	labels := Labels{"plan": "pro", "reseller": "acme"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"plan=pro", true},
+		{"plan=basic", false},
+		{"reseller", true},
+		{"!reseller", false},
+		{"!trial,plan!=basic", true},
+	}
+	for _, tt := range tests {
+		sel, err := ParseLabelSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseLabelSelector(%q) error = %v", tt.selector, err)
+		}
+		if got := sel.Matches(labels); got != tt.want {
+			t.Errorf("Selector %q: expected %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+	if _, err := ParseLabelSelector("bad key=1"); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+}
@@ -0,0 +1,271 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Panel log levels, from most to least verbose.
+const (
+	LogDebug   = "debug"
+	LogInfo    = "info"
+	LogNotice  = "notice"
+	LogWarning = "warning"
+	LogError   = "error"
+)
+
+// LogRecord is a parsed panel or Xray log line. Times have no zone in the
+// logs and are returned as UTC wall clock times of the server.
+type LogRecord struct {
+	// Zero if the line has no recognizable time.
+	Time time.Time
+	// Lower case level such as "info" or "warning". Empty for Xray access
+	// log lines.
+	Level   string
+	Message string
+	Raw     string
+}
+
+// XrayLogFilter selects Xray access log lines.
+type XrayLogFilter struct {
+	// Only lines containing Filter.
+	Filter      string
+	ShowDirect  bool
+	ShowBlocked bool
+	ShowProxy   bool
+}
+
+var (
+	// 2024/05/01 12:00:00 INFO - message
+	panelLogLine = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) ([A-Z]+) - (.*)$`)
+	// 2024/05/01 12:00:00.123456 [Warning] message
+	xrayLogLine = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) (?:\[(\w+)\] )?(.*)$`)
+	// May  1 12:00:00 host x-ui[123]: message
+	journalPrefix = regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \S+ [^:\s]+: `)
+)
+
+// ParseLogLine parses a line of the panel log, the panel log read from the
+// system journal, or the Xray error or access log. Unrecognized lines are
+// returned with only Message and Raw set.
+func ParseLogLine(line string) LogRecord {
+	record := LogRecord{Message: line, Raw: line}
+	text := line
+	if loc := journalPrefix.FindStringIndex(text); loc != nil {
+		text = text[loc[1]:]
+		record.Message = text
+	}
+	if m := panelLogLine.FindStringSubmatch(text); m != nil {
+		record.Time, _ = time.Parse("2006/01/02 15:04:05", m[1])
+		record.Level = strings.ToLower(m[2])
+		record.Message = m[3]
+		return record
+	}
+	if m := xrayLogLine.FindStringSubmatch(text); m != nil {
+		record.Time, _ = time.Parse("2006/01/02 15:04:05.999999", m[1])
+		record.Level = strings.ToLower(m[2])
+		record.Message = m[3]
+	}
+	return record
+}
+
+// GetPanelLogs returns the last count lines of the panel log at level or
+// above, oldest first. With syslog the panel reads them from the system
+// journal.
+func (c *Client) GetPanelLogs(ctx context.Context, count int, level string, syslog bool) ([]LogRecord, error) {
+	lines, err := c.panelLogLines(ctx, count, level, syslog)
+	if err != nil {
+		return nil, err
+	}
+	return parseLogLines(lines), nil
+}
+
+// GetXrayLogs returns the last count lines of the Xray log matching filter,
+// oldest first.
+func (c *Client) GetXrayLogs(ctx context.Context, count int, filter XrayLogFilter) ([]LogRecord, error) {
+	lines, err := c.xrayLogLines(ctx, count, filter)
+	if err != nil {
+		return nil, err
+	}
+	return parseLogLines(lines), nil
+}
+
+// FollowPanelLogs polls the panel log every interval and calls fn with each
+// new line, starting with the last count lines. It returns ctx.Err() once
+// ctx is done, or the error of a failed poll or a non-positive interval.
+func (c *Client) FollowPanelLogs(ctx context.Context, interval time.Duration, count int, level string, syslog bool, fn func(LogRecord)) error {
+	return followLogs(ctx, interval, func(ctx context.Context) ([]string, error) {
+		return c.panelLogLines(ctx, count, level, syslog)
+	}, fn)
+}
+
+// FollowXrayLogs is FollowPanelLogs for the Xray log.
+func (c *Client) FollowXrayLogs(ctx context.Context, interval time.Duration, count int, filter XrayLogFilter, fn func(LogRecord)) error {
+	return followLogs(ctx, interval, func(ctx context.Context) ([]string, error) {
+		return c.xrayLogLines(ctx, count, filter)
+	}, fn)
+}
+
+// panelLogLines returns panel log lines oldest first.
+func (c *Client) panelLogLines(ctx context.Context, count int, level string, syslog bool) ([]string, error) {
+	form := url.Values{}
+	form.Set("level", level)
+	form.Set("syslog", strconv.FormatBool(syslog))
+	lines, err := c.logLines(ctx, "/server/logs/"+strconv.Itoa(count), form)
+	if err != nil {
+		return nil, err
+	}
+	// The panel's in-memory log is returned newest first, the journal
+	// oldest first.
+	if !syslog {
+		slices.Reverse(lines)
+	}
+	return lines, nil
+}
+
+// xrayLogLines returns Xray log lines oldest first.
+func (c *Client) xrayLogLines(ctx context.Context, count int, filter XrayLogFilter) ([]string, error) {
+	form := url.Values{}
+	form.Set("filter", filter.Filter)
+	form.Set("showDirect", strconv.FormatBool(filter.ShowDirect))
+	form.Set("showBlocked", strconv.FormatBool(filter.ShowBlocked))
+	form.Set("showProxy", strconv.FormatBool(filter.ShowProxy))
+	lines, err := c.logLines(ctx, "/server/xraylogs/"+strconv.Itoa(count), form)
+	if err != nil {
+		return nil, err
+	}
+	// Panel versions differ in the order, the line times tell.
+	if len(lines) > 1 && ParseLogLine(lines[0]).Time.After(ParseLogLine(lines[len(lines)-1]).Time) {
+		slices.Reverse(lines)
+	}
+	return lines, nil
+}
+
+func (c *Client) logLines(ctx context.Context, path string, form url.Values) ([]string, error) {
+	resp := &ApiResponse{}
+	err := c.DoForm(ctx, http.MethodPost, path, form, resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%s", resp.Msg)
+	}
+	return decodeLogLines(resp.Obj)
+}
+
+// decodeLogLines accepts a list of lines, or of access log entries as
+// newer panels return them for the Xray log.
+func decodeLogLines(obj json.RawMessage) ([]string, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(obj, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal logs: %w", err)
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		var line string
+		if err := json.Unmarshal(entry, &line); err == nil {
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		var e struct {
+			DateTime    time.Time
+			FromAddress string
+			ToAddress   string
+			Inbound     string
+			Outbound    string
+			Email       string
+		}
+		if err := json.Unmarshal(entry, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal log entry: %w", err)
+		}
+		line = fmt.Sprintf("%s from %s accepted %s [%s >> %s]",
+			e.DateTime.Format("2006/01/02 15:04:05.000000"), e.FromAddress, e.ToAddress, e.Inbound, e.Outbound)
+		if e.Email != "" {
+			line += " email: " + e.Email
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func parseLogLines(lines []string) []LogRecord {
+	records := make([]LogRecord, len(lines))
+	for i, line := range lines {
+		records[i] = ParseLogLine(line)
+	}
+	return records
+}
+
+func followLogs(ctx context.Context, interval time.Duration, fetch func(context.Context) ([]string, error), fn func(LogRecord)) error {
+	if err := checkPollInterval(interval); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev []string
+	for {
+		lines, err := fetch(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		for _, line := range newLogLines(prev, lines) {
+			fn(ParseLogLine(line))
+		}
+		prev = lines
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// newLogLines returns the lines of cur after the longest overlap between
+// the end of prev and the start of cur. Without overlap all of cur is new.
+func newLogLines(prev, cur []string) []string {
+	for k := min(len(prev), len(cur)); k > 0; k-- {
+		if equalLines(prev[len(prev)-k:], cur[:k]) {
+			return cur[k:]
+		}
+	}
+	return cur
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
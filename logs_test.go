@@ -0,0 +1,172 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		time    time.Time
+		level   string
+		message string
+	}{
+		{
+			"2024/05/01 12:00:03 INFO - Xray is running",
+			time.Date(2024, 5, 1, 12, 0, 3, 0, time.UTC), "info", "Xray is running",
+		},
+		{
+			"May  1 12:00:03 vps x-ui[812]: 2024/05/01 12:00:03 WARNING - failed to get traffic",
+			time.Date(2024, 5, 1, 12, 0, 3, 0, time.UTC), "warning", "failed to get traffic",
+		},
+		{
+			"2024/05/01 12:00:03.250000 [Warning] core: Xray 25.1.30 started",
+			time.Date(2024, 5, 1, 12, 0, 3, 250000000, time.UTC), "warning", "core: Xray 25.1.30 started",
+		},
+		{
+			"2024/05/01 12:00:03.5 from 10.0.0.2:51234 accepted tcp:example.com:443 [vless-in >> direct] email: alice",
+			time.Date(2024, 5, 1, 12, 0, 3, 500000000, time.UTC), "", "from 10.0.0.2:51234 accepted tcp:example.com:443 [vless-in >> direct] email: alice",
+		},
+		{"panic: runtime error", time.Time{}, "", "panic: runtime error"},
+	}
+	for _, test := range tests {
+		record := ParseLogLine(test.line)
+		if !record.Time.Equal(test.time) || record.Level != test.level || record.Message != test.message || record.Raw != test.line {
+			t.Errorf("ParseLogLine(%q) = %+v", test.line, record)
+		}
+	}
+}
+
+func TestDecodeLogLines(t *testing.T) {
+	obj := json.RawMessage(`["first\n", "", {"DateTime": "2024-05-01T12:00:03Z", "FromAddress": "10.0.0.2:51234", "ToAddress": "tcp:example.com:443", "Inbound": "vless-in", "Outbound": "direct", "Email": "alice"}]`)
+	lines, err := decodeLogLines(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"first",
+		"2024/05/01 12:00:03.000000 from 10.0.0.2:51234 accepted tcp:example.com:443 [vless-in >> direct] email: alice",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+}
+
+func TestNewLogLines(t *testing.T) {
+	tests := []struct {
+		prev, cur, expected []string
+	}{
+		{nil, []string{"a", "b"}, []string{"a", "b"}},
+		{[]string{"a", "b", "c"}, []string{"b", "c", "d", "e"}, []string{"d", "e"}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{}},
+		{[]string{"a", "b"}, []string{"x", "y"}, []string{"x", "y"}},
+		{[]string{"a", "x", "x"}, []string{"x", "x", "y"}, []string{"y"}},
+	}
+	for _, test := range tests {
+		if got := newLogLines(test.prev, test.cur); !slices.Equal(got, test.expected) {
+			t.Errorf("newLogLines(%q, %q) = %q, expected %q", test.prev, test.cur, got, test.expected)
+		}
+	}
+}
+
+// newestFirstLogs reads the recorded panel log, which the panel returns
+// newest first, as lines.
+func newestFirstLogs(t *testing.T) []string {
+	b, err := os.ReadFile("testdata/panel_logs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Obj []string `json:"obj"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Obj
+}
+
+func TestGetPanelLogsOrder(t *testing.T) {
+	p := newFakePanel(t)
+	lines := newestFirstLogs(t)
+	p.handleObj("/server/logs/5", lines)
+
+	records, err := p.client().GetPanelLogs(context.Background(), 5, LogInfo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || records[0].Message != "Starting x-ui 2.5.8" || records[4].Level != "warning" {
+		t.Errorf("Expected records oldest first, got %+v", records)
+	}
+}
+
+func TestFollowPanelLogs(t *testing.T) {
+	p := newFakePanel(t)
+	lines := newestFirstLogs(t)
+	var mu sync.Mutex
+	polls := 0
+	// The log grows by a line on every poll, newest first like the panel
+	// returns it.
+	p.handle("/server/logs/3", func(*http.Request) (int, []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		end := max(len(lines)-polls, 3)
+		polls++
+		return apiSuccess(lines[end-3 : end])
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var messages []string
+	err := p.client().FollowPanelLogs(ctx, time.Millisecond, 3, LogInfo, false, func(r LogRecord) {
+		messages = append(messages, r.Message)
+		if len(messages) == len(lines) {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the follow to be cancelled, got %v", err)
+	}
+	expected := []string{
+		"Starting x-ui 2.5.8",
+		"Web server running HTTP on [::]:2053",
+		"Xray is running",
+		"Xray stopped",
+		"failed to get client traffic: xray is not running",
+	}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("Expected %q, got %q", expected, messages)
+	}
+}
+
+func TestFollowLogsInterval(t *testing.T) {
+	c := newFakePanel(t).client()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := c.FollowXrayLogs(context.Background(), interval, 10, XrayLogFilter{}, func(LogRecord) {}); err == nil {
+			t.Errorf("Expected an error for interval %v", interval)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// SIP008 is a Shadowsocks SIP008 online configuration document.
+type SIP008 struct {
+	Version int            `json:"version"`
+	Servers []SIP008Server `json:"servers"`
+	// Outline extensions, left out when zero.
+	BytesUsed      ByteSize `json:"bytes_used,omitempty"`
+	BytesRemaining ByteSize `json:"bytes_remaining,omitempty"`
+}
+
+// SIP008Server is one server of a SIP008 document.
+type SIP008Server struct {
+	ID         string `json:"id,omitempty"`
+	Remarks    string `json:"remarks,omitempty"`
+	Server     string `json:"server"`
+	ServerPort int    `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin,omitempty"`
+	PluginOpts string `json:"plugin_opts,omitempty"`
+}
+
+// outlineMethods are the ciphers Outline clients support.
+var outlineMethods = map[string]bool{
+	"chacha20-ietf-poly1305": true,
+	"aes-128-gcm":            true,
+	"aes-192-gcm":            true,
+	"aes-256-gcm":            true,
+}
+
+// shadowsocksServers returns the SIP008 servers a client of a Shadowsocks
+// inbound connects to, one per share target.
+func (i Inbound) shadowsocksServers(client InboundClient, opts ShareLinkOptions) ([]SIP008Server, error) {
+	if i.Protocol != "shadowsocks" {
+		return nil, fmt.Errorf("inbound %d is %s, not shadowsocks", i.ID, i.Protocol)
+	}
+	stream, err := i.GetStreamSettings()
+	if err != nil && i.StreamSettings != "" {
+		return nil, fmt.Errorf("invalid stream settings: %w", err)
+	}
+	if stream.Network != "" && stream.Network != "tcp" {
+		return nil, fmt.Errorf("shadowsocks over %s can't be described by an access key", stream.Network)
+	}
+	method, password, err := i.shadowsocksCredentials(client)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := ssCiphers[method]; ok {
+		method = m
+	}
+	targets, err := i.shareTargets(stream, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]SIP008Server, 0, len(targets))
+	for _, t := range targets {
+		if t.security != "" && t.security != "none" {
+			return nil, errors.New("shadowsocks over TLS can't be described by an access key")
+		}
+		servers = append(servers, SIP008Server{
+			Remarks:    Remark(opts.RemarkModel, i.Remark, client.Email, t.extra),
+			Server:     t.address,
+			ServerPort: t.port,
+			Password:   password,
+			Method:     method,
+		})
+	}
+	return servers, nil
+}
+
+// OutlineKeys builds Outline access keys for a client of a Shadowsocks
+// inbound, one per address like ShareLinks. Outline only supports the AEAD
+// ciphers, not Shadowsocks-2022.
+func (i Inbound) OutlineKeys(client InboundClient, opts ShareLinkOptions) ([]string, error) {
+	servers, err := i.shadowsocksServers(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if !outlineMethods[s.Method] {
+			return nil, fmt.Errorf("method %q is not supported by Outline", s.Method)
+		}
+		u := url.URL{
+			Scheme:   "ss",
+			User:     url.User(base64.RawURLEncoding.EncodeToString([]byte(s.Method + ":" + s.Password))),
+			Host:     net.JoinHostPort(s.Server, strconv.Itoa(s.ServerPort)),
+			Path:     "/",
+			RawQuery: "outline=1",
+			Fragment: s.Remarks,
+		}
+		keys = append(keys, u.String())
+	}
+	return keys, nil
+}
+
+// SIP008 builds the SIP008 server list of a client of a Shadowsocks inbound.
+func (i Inbound) SIP008(client InboundClient, opts ShareLinkOptions) (*SIP008, error) {
+	servers, err := i.shadowsocksServers(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SIP008{Version: 1, Servers: servers}, nil
+}
+
+// GetClientSIP008 builds the SIP008 document of the client with the given
+// email, including the traffic used and remaining.
+func (c *Client) GetClientSIP008(ctx context.Context, email string) (*SIP008, error) {
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inbound, client, err := findClient(inbounds.Obj, email)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := c.shareLinkOptions(ctx, *inbound)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := inbound.SIP008(*client, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range inbound.ClientStats {
+		if stat.Email == email {
+			doc.BytesUsed, doc.BytesRemaining = stat.Used(), stat.Remaining()
+		}
+	}
+	return doc, nil
+}
+
+// ParseSIP008 parses a SIP008 document into shadowsocks outbounds tagged
+// with the server remarks. Servers using plugins are an error since Xray
+// can't run them.
+func ParseSIP008(data []byte) ([]XrayOutbound, error) {
+	var doc SIP008
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid SIP008 document: %w", err)
+	}
+	if doc.Version != 1 {
+		return nil, fmt.Errorf("unsupported SIP008 version %d", doc.Version)
+	}
+	outbounds := make([]XrayOutbound, 0, len(doc.Servers))
+	for n, s := range doc.Servers {
+		if s.Plugin != "" {
+			return nil, fmt.Errorf("server %d: shadowsocks plugin %q is not supported by xray", n, s.Plugin)
+		}
+		if s.Server == "" || s.ServerPort <= 0 || s.ServerPort > 65535 || s.Method == "" || s.Password == "" {
+			return nil, fmt.Errorf("server %d: missing server, port, method or password", n)
+		}
+		outbounds = append(outbounds, *shadowsocksOutbound(valueOr(s.Remarks, s.ID), s.Server, s.ServerPort, s.Method, s.Password))
+	}
+	return outbounds, nil
+}
+
+// shadowsocksOutbound builds a shadowsocks outbound to one server.
+func shadowsocksOutbound(tag, address string, port int, method, password string) *XrayOutbound {
+	return &XrayOutbound{
+		Tag:      tag,
+		Protocol: "shadowsocks",
+		Settings: map[string]interface{}{
+			"servers": []map[string]interface{}{
+				{
+					"address":  address,
+					"port":     port,
+					"method":   method,
+					"password": password,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,98 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOutlineKeys(t *testing.T) {
+	inbound := Inbound{
+		Remark:         "ss",
+		Port:           8388,
+		Protocol:       "shadowsocks",
+		Settings:       `{"method":"aes-256-gcm","password":"","network":"tcp,udp","clients":[{"email":"bob","password":"pw"}]}`,
+		StreamSettings: `{"network":"tcp","security":"none"}`,
+	}
+	clients, _ := inbound.Clients()
+	keys, err := inbound.OutlineKeys(clients[0], ShareLinkOptions{Address: "1.2.3.4"})
+	if err != nil {
+		t.Fatalf("OutlineKeys() error = %v", err)
+	}
+	want := "ss://YWVzLTI1Ni1nY206cHc@1.2.3.4:8388/?outline=1#ss-bob"
+	if len(keys) != 1 || keys[0] != want {
+		t.Errorf("Expected %s, got %v", want, keys)
+	}
+
+	// Outline access keys are also valid SIP002 links.
+	outbound, err := ParseShareLink(keys[0])
+	if err != nil {
+		t.Fatalf("ParseShareLink() error = %v", err)
+	}
+	server := outbound.Settings["servers"].([]map[string]interface{})[0]
+	if server["method"] != "aes-256-gcm" || server["password"] != "pw" {
+		t.Errorf("Unexpected server %v", server)
+	}
+
+	inbound.Settings = `{"method":"2022-blake3-aes-128-gcm","password":"c2VydmVy","clients":[{"email":"bob","password":"dXNlcg=="}]}`
+	if _, err := inbound.OutlineKeys(clients[0], ShareLinkOptions{Address: "1.2.3.4"}); err == nil {
+		t.Error("Expected error for a Shadowsocks-2022 method")
+	}
+	clients, _ = inbound.Clients()
+	doc, err := inbound.SIP008(clients[0], ShareLinkOptions{Address: "1.2.3.4"})
+	if err != nil {
+		t.Fatalf("SIP008() error = %v", err)
+	}
+	if doc.Version != 1 || len(doc.Servers) != 1 || doc.Servers[0].Password != "c2VydmVy:dXNlcg==" {
+		t.Errorf("Unexpected SIP008 document %+v", doc)
+	}
+
+	inbound.StreamSettings = `{"network":"ws","security":"none"}`
+	if _, err := inbound.SIP008(clients[0], ShareLinkOptions{Address: "1.2.3.4"}); err == nil {
+		t.Error("Expected error for shadowsocks over ws")
+	}
+}
+
+func TestParseSIP008(t *testing.T) {
+	doc := SIP008{Version: 1, Servers: []SIP008Server{
+		{Remarks: "a", Server: "1.2.3.4", ServerPort: 8388, Method: "aes-128-gcm", Password: "x"},
+		{ID: "27b8a625-4f4b-4428-9f0f-8a2317db7c79", Server: "example.com", ServerPort: 443, Method: "chacha20-ietf-poly1305", Password: "y"},
+	}}
+	data, _ := json.Marshal(doc)
+	outbounds, err := ParseSIP008(data)
+	if err != nil {
+		t.Fatalf("ParseSIP008() error = %v", err)
+	}
+	if len(outbounds) != 2 || outbounds[0].Tag != "a" || outbounds[1].Tag != doc.Servers[1].ID {
+		t.Fatalf("Unexpected outbounds %+v", outbounds)
+	}
+	server := outbounds[1].Settings["servers"].([]map[string]interface{})[0]
+	if server["address"] != "example.com" || server["port"] != 443 || server["password"] != "y" {
+		t.Errorf("Unexpected server %v", server)
+	}
+
+	for _, bad := range []string{
+		`{"version":2,"servers":[]}`,
+		`{"version":1,"servers":[{"server":"a","server_port":1,"method":"aes-128-gcm","password":"x","plugin":"obfs-local"}]}`,
+		`{"version":1,"servers":[{"server":"a","server_port":0,"method":"aes-128-gcm","password":"x"}]}`,
+	} {
+		if _, err := ParseSIP008([]byte(bad)); err == nil {
+			t.Errorf("ParseSIP008(%s) expected error", bad)
+		}
+	}
+}
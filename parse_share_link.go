@@ -0,0 +1,447 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseShareLink parses a vless://, vmess://, trojan:// or ss:// link into an
+// XrayOutbound, including transport and TLS/Reality settings.
+func ParseShareLink(link string) (*XrayOutbound, error) {
+	link = strings.TrimSpace(link)
+	scheme, _, found := strings.Cut(link, "://")
+	if !found {
+		return nil, fmt.Errorf("invalid share link: missing scheme")
+	}
+	switch strings.ToLower(scheme) {
+	case "vless":
+		return parseVlessURL(link, true)
+	case "vmess":
+		return parseVmessLink(link)
+	case "trojan":
+		return parseTrojanLink(link)
+	case "ss":
+		return parseShadowsocksLink(link)
+	default:
+		return nil, fmt.Errorf("unsupported share link scheme %q", scheme)
+	}
+}
+
+// decodeBase64 accepts standard and URL-safe base64, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// parseHostPort validates the address and port of a link.
+func parseHostPort(u *url.URL, defaultPort string) (string, int, error) {
+	host := u.Hostname()
+	if host == "" {
+		return "", 0, fmt.Errorf("missing host")
+	}
+	portStr := u.Port()
+	if portStr == "" {
+		portStr = defaultPort
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, port, nil
+}
+
+// streamSettingsFromParams builds outbound stream settings from the query
+// parameters of a share link. defaultSecurity applies when the link has no
+// security parameter. Unless strict, a missing type is left out instead of
+// defaulting to tcp, and unknown types and security values are copied
+// through instead of rejected, as ParseVlessURL always did.
+func streamSettingsFromParams(params url.Values, defaultSecurity string, strict bool) (map[string]interface{}, error) {
+	network := params.Get("type")
+	if network == "" && strict {
+		network = "tcp"
+	}
+	streamSettings := map[string]interface{}{
+		"network": network,
+	}
+
+	switch network {
+	case "tcp", "raw":
+		headerType := params.Get("headerType")
+		if headerType == "" {
+			headerType = "none"
+		}
+		header := map[string]interface{}{
+			"type": headerType,
+		}
+		if headerType == "http" {
+			request := map[string]interface{}{}
+			if path := params.Get("path"); path != "" {
+				request["path"] = strings.Split(path, ",")
+			}
+			if host := params.Get("host"); host != "" {
+				request["headers"] = map[string]interface{}{
+					"Host": strings.Split(host, ","),
+				}
+			}
+			if len(request) > 0 {
+				header["request"] = request
+			}
+		}
+		streamSettings[network+"Settings"] = map[string]interface{}{
+			"header": header,
+		}
+	case "kcp", "mkcp":
+		streamSettings["network"] = "kcp"
+		kcp := map[string]interface{}{
+			"header": map[string]interface{}{
+				"type": valueOr(params.Get("headerType"), "none"),
+			},
+		}
+		if seed := params.Get("seed"); seed != "" {
+			kcp["seed"] = seed
+		}
+		streamSettings["kcpSettings"] = kcp
+	case "ws":
+		streamSettings["wsSettings"] = map[string]interface{}{
+			"path": valueOr(params.Get("path"), "/"),
+			"host": params.Get("host"),
+		}
+	case "grpc":
+		grpc := map[string]interface{}{
+			"serviceName": params.Get("serviceName"),
+			"multiMode":   params.Get("mode") == "multi",
+		}
+		if authority := params.Get("authority"); authority != "" {
+			grpc["authority"] = authority
+		}
+		streamSettings["grpcSettings"] = grpc
+	case "httpupgrade":
+		streamSettings["httpupgradeSettings"] = map[string]interface{}{
+			"path": valueOr(params.Get("path"), "/"),
+			"host": params.Get("host"),
+		}
+	case "xhttp", "splithttp":
+		streamSettings["network"] = "xhttp"
+		xhttp := map[string]interface{}{
+			"path": valueOr(params.Get("path"), "/"),
+			"host": params.Get("host"),
+			"mode": valueOr(params.Get("mode"), "auto"),
+		}
+		if extra := params.Get("extra"); extra != "" {
+			var extraObj map[string]interface{}
+			if err := json.Unmarshal([]byte(extra), &extraObj); err != nil {
+				return nil, fmt.Errorf("invalid xhttp extra: %w", err)
+			}
+			xhttp["extra"] = extraObj
+		}
+		streamSettings["xhttpSettings"] = xhttp
+	default:
+		if strict {
+			return nil, fmt.Errorf("unsupported transport type %q", network)
+		}
+	}
+
+	security := params.Get("security")
+	if security == "" {
+		security = defaultSecurity
+	}
+	if security == "" {
+		return streamSettings, nil
+	}
+	streamSettings["security"] = security
+	switch security {
+	case "none":
+	case "reality":
+		if params.Get("pbk") == "" && strict {
+			return nil, fmt.Errorf("reality link is missing the public key (pbk)")
+		}
+		streamSettings["realitySettings"] = map[string]interface{}{
+			"publicKey":   params.Get("pbk"),
+			"fingerprint": params.Get("fp"),
+			"serverName":  params.Get("sni"),
+			"shortId":     params.Get("sid"),
+			"spiderX":     params.Get("spx"),
+		}
+	case "tls":
+		tlsSettings := map[string]interface{}{
+			"serverName": params.Get("sni"),
+		}
+		if alpn := params.Get("alpn"); alpn != "" {
+			tlsSettings["alpn"] = strings.Split(alpn, ",")
+		}
+		if fp := params.Get("fp"); fp != "" {
+			tlsSettings["fingerprint"] = fp
+		}
+		if insecure := params.Get("allowInsecure"); insecure == "1" || insecure == "true" {
+			tlsSettings["allowInsecure"] = true
+		}
+		if ech := params.Get("ech"); ech != "" {
+			tlsSettings["echConfigList"] = ech
+		}
+		streamSettings["tlsSettings"] = tlsSettings
+	default:
+		if strict {
+			return nil, fmt.Errorf("unsupported security %q", security)
+		}
+	}
+	return streamSettings, nil
+}
+
+func valueOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// looseNumber is a number that some link generators write as a string.
+type looseNumber string
+
+func (n *looseNumber) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*n = looseNumber(s)
+		return nil
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*n = looseNumber(num)
+	return nil
+}
+
+// vmessLinkJSON is the payload of a vmess:// link.
+type vmessLinkJSON struct {
+	V             looseNumber `json:"v"`
+	Ps            string      `json:"ps"`
+	Add           string      `json:"add"`
+	Port          looseNumber `json:"port"`
+	ID            string      `json:"id"`
+	Aid           looseNumber `json:"aid"`
+	Scy           string      `json:"scy"`
+	Net           string      `json:"net"`
+	Type          string      `json:"type"`
+	Host          string      `json:"host"`
+	Path          string      `json:"path"`
+	TLS           string      `json:"tls"`
+	Sni           string      `json:"sni"`
+	Alpn          string      `json:"alpn"`
+	Fp            string      `json:"fp"`
+	Authority     string      `json:"authority"`
+	AllowInsecure interface{} `json:"allowInsecure"`
+}
+
+func parseVmessLink(link string) (*XrayOutbound, error) {
+	payload := link[strings.Index(link, "://")+3:]
+	if i := strings.IndexAny(payload, "?#"); i >= 0 {
+		return nil, fmt.Errorf("invalid vmess link: expected base64 JSON, found %q", payload[i:i+1])
+	}
+	data, err := decodeBase64(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vmess link: %w", err)
+	}
+	var v vmessLinkJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("invalid vmess link JSON: %w", err)
+	}
+	if v.ID == "" {
+		return nil, fmt.Errorf("missing id in vmess link")
+	}
+	if v.Add == "" {
+		return nil, fmt.Errorf("missing address in vmess link")
+	}
+	port, err := strconv.Atoi(string(v.Port))
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %q in vmess link", string(v.Port))
+	}
+	alterID := 0
+	if v.Aid != "" {
+		if alterID, err = strconv.Atoi(string(v.Aid)); err != nil {
+			return nil, fmt.Errorf("invalid aid %q in vmess link", string(v.Aid))
+		}
+	}
+
+	// Translate the vmess keys into link parameters.
+	params := url.Values{}
+	network := valueOr(v.Net, "tcp")
+	params.Set("type", network)
+	switch network {
+	case "tcp", "raw":
+		params.Set("headerType", v.Type)
+		params.Set("host", v.Host)
+		params.Set("path", v.Path)
+	case "kcp", "mkcp":
+		params.Set("headerType", v.Type)
+		params.Set("seed", v.Path)
+	case "ws", "httpupgrade":
+		params.Set("host", v.Host)
+		params.Set("path", v.Path)
+	case "grpc":
+		params.Set("serviceName", v.Path)
+		params.Set("authority", v.Authority)
+		if v.Type == "multi" {
+			params.Set("mode", "multi")
+		}
+	case "xhttp", "splithttp":
+		params.Set("host", v.Host)
+		params.Set("path", v.Path)
+		params.Set("mode", v.Type)
+	}
+	if v.TLS == "tls" {
+		params.Set("security", "tls")
+		params.Set("sni", v.Sni)
+		params.Set("alpn", v.Alpn)
+		params.Set("fp", v.Fp)
+		if v.AllowInsecure == true || v.AllowInsecure == "1" || v.AllowInsecure == "true" {
+			params.Set("allowInsecure", "1")
+		}
+	}
+	streamSettings, err := streamSettingsFromParams(params, "none", true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vmess link: %w", err)
+	}
+
+	return &XrayOutbound{
+		Tag:      v.Ps,
+		Protocol: "vmess",
+		Settings: map[string]interface{}{
+			"vnext": []map[string]interface{}{
+				{
+					"address": v.Add,
+					"port":    port,
+					"users": []map[string]interface{}{
+						{
+							"id":       v.ID,
+							"alterId":  alterID,
+							"security": valueOr(v.Scy, "auto"),
+						},
+					},
+				},
+			},
+		},
+		StreamSettings: streamSettings,
+	}, nil
+}
+
+func parseTrojanLink(link string) (*XrayOutbound, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	password := u.User.Username()
+	if password == "" {
+		return nil, fmt.Errorf("missing password in trojan link")
+	}
+	host, port, err := parseHostPort(u, "443")
+	if err != nil {
+		return nil, fmt.Errorf("invalid trojan link: %w", err)
+	}
+	params := u.Query()
+	if params.Get("sni") == "" && params.Get("peer") != "" {
+		params.Set("sni", params.Get("peer"))
+	}
+	streamSettings, err := streamSettingsFromParams(params, "tls", true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trojan link: %w", err)
+	}
+	return &XrayOutbound{
+		Tag:      u.Fragment,
+		Protocol: "trojan",
+		Settings: map[string]interface{}{
+			"servers": []map[string]interface{}{
+				{
+					"address":  host,
+					"port":     port,
+					"password": password,
+				},
+			},
+		},
+		StreamSettings: streamSettings,
+	}, nil
+}
+
+// parseShadowsocksLink handles SIP002 links, with base64 or percent-encoded
+// user info, and the legacy form that base64 encodes everything before the
+// fragment.
+func parseShadowsocksLink(link string) (*XrayOutbound, error) {
+	rest := link[strings.Index(link, "://")+3:]
+	body, fragment, _ := strings.Cut(rest, "#")
+	if !strings.Contains(body, "@") {
+		// Legacy ss://base64(method:password@host:port)
+		main, query, _ := strings.Cut(body, "?")
+		decoded, err := decodeBase64(strings.TrimSuffix(main, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid shadowsocks link: %w", err)
+		}
+		link = "ss://" + string(decoded)
+		if query != "" {
+			link += "?" + query
+		}
+		if fragment != "" {
+			link += "#" + fragment
+		}
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.User == nil {
+		return nil, fmt.Errorf("missing method and password in shadowsocks link")
+	}
+	var userinfo string
+	if password, ok := u.User.Password(); ok {
+		userinfo = u.User.Username() + ":" + password
+	} else {
+		decoded, err := decodeBase64(u.User.Username())
+		if err != nil {
+			return nil, fmt.Errorf("invalid shadowsocks user info: %w", err)
+		}
+		userinfo = string(decoded)
+	}
+	method, password, found := strings.Cut(userinfo, ":")
+	if !found || method == "" || password == "" {
+		return nil, fmt.Errorf("invalid shadowsocks user info: expected method:password")
+	}
+	host, port, err := parseHostPort(u, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid shadowsocks link: %w", err)
+	}
+
+	params := u.Query()
+	if plugin := params.Get("plugin"); plugin != "" {
+		return nil, fmt.Errorf("shadowsocks plugin %q is not supported by xray", plugin)
+	}
+	outbound := shadowsocksOutbound(u.Fragment, host, port, method, password)
+	if params.Get("type") != "" || params.Get("security") != "" {
+		if outbound.StreamSettings, err = streamSettingsFromParams(params, "none", true); err != nil {
+			return nil, fmt.Errorf("invalid shadowsocks link: %w", err)
+		}
+	}
+	return outbound, nil
+}
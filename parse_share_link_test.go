@@ -0,0 +1,172 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseShareLink(t *testing.T) {
+	vmessJSON := `{"v":"2","ps":"vm","add":"example.com","port":"443","id":"uuid","aid":"","scy":"auto","net":"ws","type":"none","host":"cdn.example.com","path":"/ws","tls":"tls","sni":"example.com","alpn":"h2,http/1.1","fp":"chrome"}`
+
+	tests := []struct {
+		name  string
+		link  string
+		check func(t *testing.T, outbound *XrayOutbound)
+	}{
+		{
+			name: "VMess WebSocket TLS",
+			link: "vmess://" + base64.StdEncoding.EncodeToString([]byte(vmessJSON)),
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				if outbound.Protocol != "vmess" || outbound.Tag != "vm" {
+					t.Errorf("Unexpected outbound %s/%s", outbound.Protocol, outbound.Tag)
+				}
+				server := outbound.Settings["vnext"].([]map[string]interface{})[0]
+				if server["address"] != "example.com" || server["port"] != 443 {
+					t.Errorf("Unexpected server %v", server)
+				}
+				ws := outbound.StreamSettings["wsSettings"].(map[string]interface{})
+				if ws["path"] != "/ws" || ws["host"] != "cdn.example.com" {
+					t.Errorf("Unexpected ws settings %v", ws)
+				}
+				tls := outbound.StreamSettings["tlsSettings"].(map[string]interface{})
+				if tls["serverName"] != "example.com" || tls["fingerprint"] != "chrome" {
+					t.Errorf("Unexpected tls settings %v", tls)
+				}
+			},
+		},
+		{
+			name: "Trojan gRPC",
+			link: "trojan://p%40ss@1.2.3.4:8443?type=grpc&serviceName=svc&mode=multi&security=tls&sni=example.com#tr",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				server := outbound.Settings["servers"].([]map[string]interface{})[0]
+				if server["password"] != "p@ss" || server["port"] != 8443 {
+					t.Errorf("Unexpected server %v", server)
+				}
+				grpc := outbound.StreamSettings["grpcSettings"].(map[string]interface{})
+				if grpc["serviceName"] != "svc" || grpc["multiMode"] != true {
+					t.Errorf("Unexpected grpc settings %v", grpc)
+				}
+			},
+		},
+		{
+			name: "Trojan defaults to TLS",
+			link: "trojan://secret@example.com",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				if outbound.StreamSettings["security"] != "tls" {
+					t.Errorf("Expected tls security, got %v", outbound.StreamSettings["security"])
+				}
+			},
+		},
+		{
+			name: "Shadowsocks SIP002",
+			link: "ss://" + base64.RawURLEncoding.EncodeToString([]byte("aes-256-gcm:secret")) + "@[2001:db8::1]:8388#ss",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				server := outbound.Settings["servers"].([]map[string]interface{})[0]
+				if server["method"] != "aes-256-gcm" || server["password"] != "secret" || server["address"] != "2001:db8::1" {
+					t.Errorf("Unexpected server %v", server)
+				}
+			},
+		},
+		{
+			name: "Shadowsocks 2022 plain user info",
+			link: "ss://2022-blake3-aes-128-gcm:a2V5MQ%3D%3D%3Aa2V5Mg%3D%3D@example.com:443",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				server := outbound.Settings["servers"].([]map[string]interface{})[0]
+				if server["password"] != "a2V5MQ==:a2V5Mg==" {
+					t.Errorf("Unexpected password %v", server["password"])
+				}
+			},
+		},
+		{
+			name: "Shadowsocks legacy",
+			link: "ss://" + base64.StdEncoding.EncodeToString([]byte("chacha20-ietf-poly1305:pw@example.com:8388")) + "#old",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				server := outbound.Settings["servers"].([]map[string]interface{})[0]
+				if server["address"] != "example.com" || server["port"] != 8388 || outbound.Tag != "old" {
+					t.Errorf("Unexpected outbound %v %s", server, outbound.Tag)
+				}
+			},
+		},
+		{
+			name: "VLESS xhttp",
+			link: "vless://uuid@example.com:443?type=xhttp&path=%2Fx&mode=packet-up&security=tls&sni=example.com",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				xhttp := outbound.StreamSettings["xhttpSettings"].(map[string]interface{})
+				if xhttp["path"] != "/x" || xhttp["mode"] != "packet-up" {
+					t.Errorf("Unexpected xhttp settings %v", xhttp)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbound, err := ParseShareLink(tt.link)
+			if err != nil {
+				t.Fatalf("ParseShareLink() error = %v", err)
+			}
+			tt.check(t, outbound)
+		})
+	}
+}
+
+func TestParseShareLinkErrors(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"example.com", "missing scheme"},
+		{"socks://example.com", "unsupported share link scheme"},
+		{"vmess://not base64!", "invalid vmess link"},
+		{"vmess://" + base64.StdEncoding.EncodeToString([]byte(`{"id":"x","add":"a","port":"abc"}`)), "invalid port"},
+		{"trojan://@example.com:443", "missing password"},
+		{"trojan://pw@example.com:99999", "invalid port"},
+		{"vless://uuid@example.com:443?type=quic", "unsupported transport"},
+		{"vless://uuid@example.com:443?type=h2&security=tls", "unsupported transport"},
+		{"vless://uuid@example.com:443?security=reality", "missing the public key"},
+		{"ss://" + base64.RawURLEncoding.EncodeToString([]byte("nocolon")) + "@example.com:1", "expected method:password"},
+		{"ss://" + base64.RawURLEncoding.EncodeToString([]byte("aes-128-gcm:pw")) + "@example.com:1?plugin=obfs-local", "plugin"},
+	}
+	for _, tt := range tests {
+		_, err := ParseShareLink(tt.link)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseShareLink(%q) error = %v, want %q", tt.link, err, tt.want)
+		}
+	}
+}
+
+func TestShareLinkRoundTrip(t *testing.T) {
+	clients, _ := testRealityInbound.Clients()
+	link, err := testRealityInbound.ShareLink(clients[0], ShareLinkOptions{Address: "89.169.53.31"})
+	if err != nil {
+		t.Fatalf("ShareLink() error = %v", err)
+	}
+	outbound, err := ParseShareLink(link)
+	if err != nil {
+		t.Fatalf("ParseShareLink() error = %v", err)
+	}
+	reality := outbound.StreamSettings["realitySettings"].(map[string]interface{})
+	if reality["shortId"] != "82c54a0dbca8" || reality["serverName"] != "rt.com" {
+		t.Errorf("Unexpected reality settings %v", reality)
+	}
+	user := outbound.Settings["vnext"].([]map[string]interface{})[0]["users"].([]map[string]interface{})[0]
+	if user["flow"] != VisionFlow {
+		t.Errorf("Expected flow %q, got %v", VisionFlow, user["flow"])
+	}
+}
@@ -0,0 +1,301 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SkippedOutbound is an outbound that could not be exported.
+type SkippedOutbound struct {
+	Tag string
+	Err error
+}
+
+// Outbounds builds the client's share links and parses them back into
+// outbounds, ready to be exported to other client formats.
+func (i Inbound) Outbounds(client InboundClient, opts ShareLinkOptions) ([]XrayOutbound, error) {
+	links, err := i.ShareLinks(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	outbounds := make([]XrayOutbound, 0, len(links))
+	for _, link := range links {
+		outbound, err := ParseShareLink(link)
+		if err != nil {
+			return nil, err
+		}
+		outbounds = append(outbounds, *outbound)
+	}
+	return outbounds, nil
+}
+
+// stringList is a JSON string or array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// proxyStream is the part of outbound stream settings the exporters
+// understand.
+type proxyStream struct {
+	Network     string `json:"network"`
+	Security    string `json:"security"`
+	TlsSettings struct {
+		ServerName    string   `json:"serverName"`
+		Alpn          []string `json:"alpn"`
+		Fingerprint   string   `json:"fingerprint"`
+		AllowInsecure bool     `json:"allowInsecure"`
+	} `json:"tlsSettings"`
+	RealitySettings struct {
+		PublicKey   string `json:"publicKey"`
+		ShortID     string `json:"shortId"`
+		ServerName  string `json:"serverName"`
+		Fingerprint string `json:"fingerprint"`
+	} `json:"realitySettings"`
+	TcpSettings *struct {
+		Header struct {
+			Type    string `json:"type"`
+			Request struct {
+				Method  string                `json:"method"`
+				Path    stringList            `json:"path"`
+				Headers map[string]stringList `json:"headers"`
+			} `json:"request"`
+		} `json:"header"`
+	} `json:"tcpSettings"`
+	RawSettings *struct {
+		Header struct {
+			Type    string `json:"type"`
+			Request struct {
+				Method  string                `json:"method"`
+				Path    stringList            `json:"path"`
+				Headers map[string]stringList `json:"headers"`
+			} `json:"request"`
+		} `json:"header"`
+	} `json:"rawSettings"`
+	WsSettings struct {
+		Path    string            `json:"path"`
+		Host    string            `json:"host"`
+		Headers map[string]string `json:"headers"`
+	} `json:"wsSettings"`
+	GrpcSettings struct {
+		ServiceName string `json:"serviceName"`
+		Authority   string `json:"authority"`
+		MultiMode   bool   `json:"multiMode"`
+	} `json:"grpcSettings"`
+	HttpupgradeSettings struct {
+		Path string `json:"path"`
+		Host string `json:"host"`
+	} `json:"httpupgradeSettings"`
+}
+
+// proxyOutbound is a vless, vmess, trojan or shadowsocks outbound flattened
+// into the fields the client config exporters need.
+type proxyOutbound struct {
+	Tag      string
+	Protocol string
+	Address  string
+	Port     int
+	// vless and vmess
+	ID         string
+	Flow       string
+	Encryption string
+	AlterID    int
+	// vmess security
+	Cipher string
+	// trojan and shadowsocks
+	Password string
+	Method   string
+
+	Network  string
+	Security string
+	// TLS or Reality server name, fingerprint and ALPN.
+	ServerName    string
+	Fingerprint   string
+	Alpn          []string
+	AllowInsecure bool
+	PublicKey     string
+	ShortID       string
+	// HTTP header obfuscation on tcp, when HeaderType is "http".
+	HeaderType    string
+	HeaderMethod  string
+	HeaderPaths   []string
+	HeaderHeaders map[string][]string
+	// ws, httpupgrade and grpc
+	Path        string
+	Host        string
+	ServiceName string
+}
+
+// decodeProxyOutbound reads an outbound built by ParseShareLink or decoded
+// from JSON.
+func decodeProxyOutbound(o XrayOutbound) (*proxyOutbound, error) {
+	var settings struct {
+		Vnext []struct {
+			Address string `json:"address"`
+			Port    int    `json:"port"`
+			Users   []struct {
+				ID         string      `json:"id"`
+				Flow       string      `json:"flow"`
+				Encryption string      `json:"encryption"`
+				AlterID    looseNumber `json:"alterId"`
+				Security   string      `json:"security"`
+			} `json:"users"`
+		} `json:"vnext"`
+		Servers []struct {
+			Address  string `json:"address"`
+			Port     int    `json:"port"`
+			Password string `json:"password"`
+			Method   string `json:"method"`
+		} `json:"servers"`
+	}
+	if err := remarshal(o.Settings, &settings); err != nil {
+		return nil, fmt.Errorf("invalid outbound settings: %w", err)
+	}
+
+	p := &proxyOutbound{Tag: o.Tag, Protocol: o.Protocol}
+	switch o.Protocol {
+	case "vless", "vmess":
+		if len(settings.Vnext) == 0 || len(settings.Vnext[0].Users) == 0 {
+			return nil, fmt.Errorf("%s outbound has no server or user", o.Protocol)
+		}
+		server, user := settings.Vnext[0], settings.Vnext[0].Users[0]
+		p.Address, p.Port = server.Address, server.Port
+		p.ID, p.Flow, p.Encryption, p.Cipher = user.ID, user.Flow, user.Encryption, user.Security
+		if user.AlterID != "" {
+			p.AlterID, _ = strconv.Atoi(string(user.AlterID))
+		}
+	case "trojan", "shadowsocks":
+		if len(settings.Servers) == 0 {
+			return nil, fmt.Errorf("%s outbound has no server", o.Protocol)
+		}
+		server := settings.Servers[0]
+		p.Address, p.Port = server.Address, server.Port
+		p.Password, p.Method = server.Password, server.Method
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", o.Protocol)
+	}
+	if p.Address == "" || p.Port == 0 {
+		return nil, fmt.Errorf("%s outbound is missing the server address or port", o.Protocol)
+	}
+
+	var stream proxyStream
+	if err := remarshal(o.StreamSettings, &stream); err != nil {
+		return nil, fmt.Errorf("invalid stream settings: %w", err)
+	}
+	p.Network = valueOr(stream.Network, "tcp")
+	p.Security = valueOr(stream.Security, "none")
+	switch p.Security {
+	case "tls":
+		p.ServerName = stream.TlsSettings.ServerName
+		p.Fingerprint = stream.TlsSettings.Fingerprint
+		p.Alpn = stream.TlsSettings.Alpn
+		p.AllowInsecure = stream.TlsSettings.AllowInsecure
+	case "reality":
+		p.ServerName = stream.RealitySettings.ServerName
+		p.Fingerprint = stream.RealitySettings.Fingerprint
+		p.PublicKey = stream.RealitySettings.PublicKey
+		p.ShortID = stream.RealitySettings.ShortID
+	}
+	switch p.Network {
+	case "tcp", "raw":
+		p.Network = "tcp"
+		tcp := stream.TcpSettings
+		if tcp == nil {
+			tcp = stream.RawSettings
+		}
+		if tcp != nil && tcp.Header.Type == "http" {
+			p.HeaderType = "http"
+			p.HeaderMethod = tcp.Header.Request.Method
+			p.HeaderPaths = tcp.Header.Request.Path
+			p.HeaderHeaders = map[string][]string{}
+			for k, v := range tcp.Header.Request.Headers {
+				p.HeaderHeaders[k] = v
+			}
+		}
+	case "ws":
+		p.Path = stream.WsSettings.Path
+		p.Host = hostHeader(stream.WsSettings.Host, stream.WsSettings.Headers)
+	case "httpupgrade":
+		p.Path = stream.HttpupgradeSettings.Path
+		p.Host = stream.HttpupgradeSettings.Host
+	case "grpc":
+		p.ServiceName = stream.GrpcSettings.ServiceName
+		p.Host = stream.GrpcSettings.Authority
+	}
+	return p, nil
+}
+
+// ssCiphers maps Xray shadowsocks methods to the names Clash and sing-box
+// use.
+var ssCiphers = map[string]string{
+	"aes-128-gcm":                   "aes-128-gcm",
+	"aes-256-gcm":                   "aes-256-gcm",
+	"chacha20-poly1305":             "chacha20-ietf-poly1305",
+	"chacha20-ietf-poly1305":        "chacha20-ietf-poly1305",
+	"xchacha20-poly1305":            "xchacha20-ietf-poly1305",
+	"xchacha20-ietf-poly1305":       "xchacha20-ietf-poly1305",
+	"none":                          "none",
+	"plain":                         "none",
+	"2022-blake3-aes-128-gcm":       "2022-blake3-aes-128-gcm",
+	"2022-blake3-aes-256-gcm":       "2022-blake3-aes-256-gcm",
+	"2022-blake3-chacha20-poly1305": "2022-blake3-chacha20-poly1305",
+}
+
+// remarshal converts generic JSON-like maps into out.
+func remarshal(in, out interface{}) error {
+	if in == nil {
+		return nil
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// uniqueName returns name, or name with a numeric suffix if taken, and marks
+// the result as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s %d", name, n)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// proxyName is the display name of an exported outbound.
+func proxyName(p *proxyOutbound) string {
+	if p.Tag != "" {
+		return p.Tag
+	}
+	return fmt.Sprintf("%s-%s:%d", p.Protocol, p.Address, p.Port)
+}
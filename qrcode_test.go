@@ -0,0 +1,114 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" as 1-M from the standard's worked example.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	got := rsRemainder(data, rsDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected ECC %v, got %v", want, got)
+	}
+}
+
+func TestQRFormatInfo(t *testing.T) {
+	tests := []struct {
+		level QRErrorCorrection
+		mask  int
+		want  int
+	}{
+		{QRLow, 0, 0x77C4},
+		{QRMedium, 0, 0x5412},
+		{QRQuartile, 5, 0x2183},
+		{QRHigh, 7, 0x083B},
+	}
+	for _, tt := range tests {
+		if got := qrFormatInfo(tt.level, tt.mask); got != tt.want {
+			t.Errorf("qrFormatInfo(%d, %d) = %#x, want %#x", tt.level, tt.mask, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		level   QRErrorCorrection
+		version int
+	}{
+		{17, QRLow, 1},
+		{18, QRLow, 2},
+		{7, QRHigh, 1},
+		{8, QRHigh, 2},
+		{2953, QRLow, 40},
+	}
+	for _, tt := range tests {
+		q, err := EncodeQR(strings.Repeat("a", tt.length), tt.level)
+		if err != nil {
+			t.Fatalf("EncodeQR(%d bytes) error = %v", tt.length, err)
+		}
+		if q.Version != tt.version || q.Size != tt.version*4+17 {
+			t.Errorf("%d bytes at level %d: expected version %d, got %d", tt.length, tt.level, tt.version, q.Version)
+		}
+	}
+	if _, err := EncodeQR(strings.Repeat("a", 2954), QRLow); err == nil {
+		t.Error("Expected error for text that doesn't fit")
+	}
+}
+
+func TestQRCodeOutputs(t *testing.T) {
+	q, err := EncodeQR("vless://8e72473d-3c52-4153-b5ba-3b06035d0ad1@89.169.53.31:443?type=tcp#de", QRMedium)
+	if err != nil {
+		t.Fatalf("EncodeQR() error = %v", err)
+	}
+
+	// Finder pattern corners and the always dark module.
+	for _, p := range [][2]int{{0, 0}, {q.Size - 1, 0}, {0, q.Size - 1}, {8, q.Size - 8}} {
+		if !q.Module(p[0], p[1]) {
+			t.Errorf("Expected dark module at %v", p)
+		}
+	}
+
+	data, err := q.PNG(3)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid PNG: %v", err)
+	}
+	if dim := (q.Size + 8) * 3; img.Bounds().Dx() != dim || img.Bounds().Dy() != dim {
+		t.Errorf("Expected %dx%d image, got %v", dim, dim, img.Bounds())
+	}
+
+	svg := q.SVG(4)
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "M4,4h1v1h-1z") {
+		t.Errorf("Unexpected SVG %.80s", svg)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(q.Terminal(), "\n"), "\n")
+	if len(lines) != (q.Size+9)/2 {
+		t.Errorf("Expected %d terminal lines, got %d", (q.Size+9)/2, len(lines))
+	}
+}
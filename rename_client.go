@@ -0,0 +1,175 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+var (
+	// ErrClientNotFound is returned when no inbound has a client with the given email.
+	ErrClientNotFound = errors.New("client not found")
+	// ErrEmailTaken is returned when an email is already used by a client of any inbound.
+	ErrEmailTaken = errors.New("email already in use")
+	// ErrTrafficNotPreserved is returned when the panel reset the traffic
+	// counters of a renamed client and they could not be restored.
+	ErrTrafficNotPreserved = errors.New("panel did not preserve traffic counters")
+)
+
+// findClient locates the client with the given email.
+func findClient(inbounds []Inbound, email string) (*Inbound, *InboundClient, error) {
+	for i := range inbounds {
+		clients, err := inbounds[i].Clients()
+		if err != nil {
+			return nil, nil, fmt.Errorf("inbound %d: %w", inbounds[i].ID, err)
+		}
+		for j := range clients {
+			if clients[j].Email == email {
+				return &inbounds[i], &clients[j], nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrClientNotFound, email)
+}
+
+// emailInUse reports whether a client or a traffic record of any inbound uses email.
+func emailInUse(inbounds []Inbound, email string) (bool, error) {
+	for _, inbound := range inbounds {
+		for _, stat := range inbound.ClientStats {
+			if stat.Email == email {
+				return true, nil
+			}
+		}
+		clients, err := inbound.Clients()
+		if err != nil {
+			return false, fmt.Errorf("inbound %d: %w", inbound.ID, err)
+		}
+		for _, client := range clients {
+			if client.Email == email {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// RenameClientEmail changes the email of a client while keeping its traffic
+// counters. The new email must not be used by any inbound. Panel versions that
+// reset counters on rename get them restored through updateClientTraffic; if
+// that isn't available either the rename is kept and an error wrapping
+// ErrTrafficNotPreserved is returned.
+func (c *Client) RenameClientEmail(ctx context.Context, oldEmail, newEmail string) error {
+	if newEmail == "" {
+		return errors.New("new email is empty")
+	}
+	if oldEmail == newEmail {
+		return nil
+	}
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return err
+	}
+	inbound, client, err := findClient(inbounds.Obj, oldEmail)
+	if err != nil {
+		return err
+	}
+	taken, err := emailInUse(inbounds.Obj, newEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", ErrEmailTaken, newEmail)
+	}
+
+	before, err := c.GetClientByEmail(ctx, oldEmail)
+	if err != nil {
+		return fmt.Errorf("failed to read traffic of %s: %w", oldEmail, err)
+	}
+
+	// The raw object keeps the fields InboundClient doesn't know, such as
+	// tgId, which the panel would otherwise drop.
+	renamed, err := renamedClientJSON(*inbound, oldEmail, newEmail)
+	if err != nil {
+		return err
+	}
+	key := ClientKey(inbound.Protocol, *client)
+	if _, err := c.updateClient(ctx, uint(inbound.ID), key, renamed); err != nil {
+		return err
+	}
+
+	after, err := c.GetClientByEmail(ctx, newEmail)
+	if err != nil {
+		return fmt.Errorf("failed to read traffic of %s: %w", newEmail, err)
+	}
+	if after.Up >= before.Up && after.Down >= before.Down {
+		return nil
+	}
+	if err := c.UpdateClientTraffic(ctx, newEmail, before.Up, before.Down); err != nil {
+		return fmt.Errorf("%w: %s had up %v down %v, now up %v down %v: %v",
+			ErrTrafficNotPreserved, newEmail, before.Up, before.Down, after.Up, after.Down, err)
+	}
+	return nil
+}
+
+// renamedClientJSON returns the client object with oldEmail from the
+// settings of inbound, with only its email changed.
+func renamedClientJSON(inbound Inbound, oldEmail, newEmail string) (json.RawMessage, error) {
+	var settings struct {
+		Clients []map[string]json.RawMessage `json:"clients"`
+	}
+	if err := json.Unmarshal([]byte(inbound.Settings), &settings); err != nil {
+		return nil, fmt.Errorf("inbound %d: %w", inbound.ID, err)
+	}
+	for _, client := range settings.Clients {
+		var email string
+		if json.Unmarshal(client["email"], &email) != nil || email != oldEmail {
+			continue
+		}
+		newEmailJSON, err := json.Marshal(newEmail)
+		if err != nil {
+			return nil, err
+		}
+		client["email"] = newEmailJSON
+		return json.Marshal(client)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrClientNotFound, oldEmail)
+}
+
+type updateClientTrafficRequest struct {
+	Upload   ByteSize `json:"upload"`
+	Download ByteSize `json:"download"`
+}
+
+// UpdateClientTraffic overwrites the traffic counters of a client. Older panel
+// versions don't have this endpoint.
+func (c *Client) UpdateClientTraffic(ctx context.Context, email string, up, down ByteSize) error {
+	resp := &ApiResponse{}
+	req := &updateClientTrafficRequest{Upload: up, Download: down}
+	err := c.Do(ctx, http.MethodPost, "/panel/api/inbounds/updateClientTraffic/"+url.PathEscape(email), req, resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
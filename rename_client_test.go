@@ -0,0 +1,154 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestClientKey(t *testing.T) {
+	client := InboundClient{ID: "8e72473d-3c52-4153-b5ba-3b06035d0ad1", Password: "trojan-pass", Email: "alice"}
+	tests := map[string]string{
+		"vless":       client.ID,
+		"vmess":       client.ID,
+		"trojan":      client.Password,
+		"shadowsocks": client.Email,
+	}
+	for protocol, expected := range tests {
+		if key := ClientKey(protocol, client); key != expected {
+			t.Errorf("ClientKey(%q) = %q, expected %q", protocol, key, expected)
+		}
+	}
+}
+
+// renamePanel serves two vless inbounds, alice with fields InboundClient
+// doesn't know on the first and bob on the second. alice has traffic and
+// the panel reports traffic after the rename from afterTraffic.
+func renamePanel(t *testing.T, afterTraffic ClientStat) *fakePanel {
+	p := newFakePanel(t)
+	p.handleObj("/panel/inbound/list", []Inbound{
+		{
+			ID:       1,
+			Protocol: "vless",
+			Settings: `{"clients":[{"id":"8e72473d-3c52-4153-b5ba-3b06035d0ad1","email":"alice","enable":true,"tgId":"12345","custom":{"a":1}}],"decryption":"none"}`,
+		},
+		{
+			ID:       2,
+			Protocol: "vless",
+			Settings: `{"clients":[{"id":"3b2c3e1f-5a0e-4a8e-9c3b-0d5f0e7b1a2c","email":"bob","enable":true}]}`,
+		},
+	})
+	p.handleObj("/panel/api/inbounds/getClientTraffics/alice", ClientStat{Email: "alice", Up: 3 * GiB, Down: 7 * GiB})
+	p.handleObj("/panel/api/inbounds/getClientTraffics/carol", afterTraffic)
+	return p
+}
+
+func TestRenameClientEmail(t *testing.T) {
+	p := renamePanel(t, ClientStat{Email: "carol", Up: 3 * GiB, Down: 7 * GiB})
+	var sent map[string]json.RawMessage
+	p.handle("/panel/inbound/updateClient/8e72473d-3c52-4153-b5ba-3b06035d0ad1", func(r *http.Request) (int, []byte) {
+		var settings struct {
+			Clients []map[string]json.RawMessage `json:"clients"`
+		}
+		if err := json.Unmarshal([]byte(r.FormValue("settings")), &settings); err != nil || len(settings.Clients) != 1 {
+			t.Errorf("Unexpected settings %q", r.FormValue("settings"))
+			return apiFailure("bad settings")
+		}
+		if r.FormValue("id") != "1" {
+			t.Errorf("Expected inbound 1, got %q", r.FormValue("id"))
+		}
+		sent = settings.Clients[0]
+		return apiSuccess(nil)
+	})
+
+	if err := p.client().RenameClientEmail(context.Background(), "alice", "carol"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"email":  `"carol"`,
+		"id":     `"8e72473d-3c52-4153-b5ba-3b06035d0ad1"`,
+		"enable": `true`,
+		"tgId":   `"12345"`,
+		"custom": `{"a":1}`,
+	}
+	if len(sent) != len(expected) {
+		t.Errorf("Expected only the email to change, sent %s", sent)
+	}
+	for key, value := range expected {
+		if string(sent[key]) != value {
+			t.Errorf("Expected %s to be %s, got %s", key, value, sent[key])
+		}
+	}
+	if n := p.callCount("/panel/api/inbounds/updateClientTraffic/carol"); n != 0 {
+		t.Errorf("Expected no traffic restore, got %d calls", n)
+	}
+}
+
+func TestRenameClientEmailRestoresTraffic(t *testing.T) {
+	p := renamePanel(t, ClientStat{Email: "carol"})
+	p.handleObj("/panel/inbound/updateClient/8e72473d-3c52-4153-b5ba-3b06035d0ad1", nil)
+	var restored updateClientTrafficRequest
+	p.handle("/panel/api/inbounds/updateClientTraffic/carol", func(r *http.Request) (int, []byte) {
+		if err := json.NewDecoder(r.Body).Decode(&restored); err != nil {
+			t.Error(err)
+		}
+		return apiSuccess(nil)
+	})
+
+	if err := p.client().RenameClientEmail(context.Background(), "alice", "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Upload != 3*GiB || restored.Download != 7*GiB {
+		t.Errorf("Expected the traffic to be restored, got %+v", restored)
+	}
+
+	// Panels without the endpoint keep the rename but report the loss.
+	p.handle("/panel/api/inbounds/updateClientTraffic/carol", func(*http.Request) (int, []byte) {
+		return http.StatusNotFound, nil
+	})
+	err := p.client().RenameClientEmail(context.Background(), "alice", "carol")
+	if !errors.Is(err, ErrTrafficNotPreserved) {
+		t.Errorf("Expected ErrTrafficNotPreserved, got %v", err)
+	}
+}
+
+func TestRenameClientEmailTaken(t *testing.T) {
+	p := renamePanel(t, ClientStat{})
+	err := p.client().RenameClientEmail(context.Background(), "alice", "bob")
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken, got %v", err)
+	}
+
+	err = p.client().RenameClientEmail(context.Background(), "dave", "carol")
+	if !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+}
+
+func TestRenameClientEmailSame(t *testing.T) {
+	p := newFakePanel(t)
+	if err := p.client().RenameClientEmail(context.Background(), "alice", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if p.loginCount() != 0 {
+		t.Error("Expected no requests when the email doesn't change")
+	}
+}
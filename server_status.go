@@ -18,7 +18,9 @@ package client3xui
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"time"
 )
 
 type ProcessState string
@@ -29,56 +31,145 @@ const (
 	Error   ProcessState = "error"
 )
 
+// UsageStat is the used and total size of memory, swap or disk.
+type UsageStat struct {
+	Current ByteSize `json:"current"`
+	Total   ByteSize `json:"total"`
+}
+
+// Percent returns the used share in percent, zero if Total is unknown.
+func (u UsageStat) Percent() float64 {
+	if u.Total <= 0 {
+		return 0
+	}
+	return float64(u.Current) / float64(u.Total) * 100
+}
+
+// Free returns the unused size.
+func (u UsageStat) Free() ByteSize {
+	if u.Current >= u.Total {
+		return 0
+	}
+	return u.Total - u.Current
+}
+
+// XrayStatus is the state of the Xray process.
+type XrayStatus struct {
+	State    ProcessState `json:"state"`
+	ErrorMsg string       `json:"errorMsg"`
+	Version  string       `json:"version"`
+}
+
+// NetIO is the current network throughput in bytes per second.
+type NetIO struct {
+	Up   ByteSize `json:"up"`
+	Down ByteSize `json:"down"`
+}
+
+// NetTraffic is the total network traffic since boot.
+type NetTraffic struct {
+	Sent ByteSize `json:"sent"`
+	Recv ByteSize `json:"recv"`
+}
+
+// PublicIP holds the server's public addresses as the panel detected them.
+type PublicIP struct {
+	IPv4 string `json:"ipv4"`
+	IPv6 string `json:"ipv6"`
+}
+
+// AppStats describes the panel process.
+type AppStats struct {
+	Threads uint32   `json:"threads"`
+	Mem     ByteSize `json:"mem"`
+	// Seconds since the panel started.
+	Uptime uint64 `json:"uptime"`
+}
+
+// UptimeDuration returns the panel's uptime.
+func (a AppStats) UptimeDuration() time.Duration {
+	return time.Duration(a.Uptime) * time.Second
+}
+
+// ServerStatus is the system and Xray status of the server.
+type ServerStatus struct {
+	Cpu         float64    `json:"cpu"`
+	CpuCores    int        `json:"cpuCores"`
+	LogicalPro  int        `json:"logicalPro"`
+	CpuSpeedMhz float64    `json:"cpuSpeedMhz"`
+	Mem         UsageStat  `json:"mem"`
+	Swap        UsageStat  `json:"swap"`
+	Disk        UsageStat  `json:"disk"`
+	Xray        XrayStatus `json:"xray"`
+	// Seconds since the server booted.
+	Uptime     uint64     `json:"uptime"`
+	Loads      []float64  `json:"loads"`
+	TcpCount   int        `json:"tcpCount"`
+	UdpCount   int        `json:"udpCount"`
+	NetIO      NetIO      `json:"netIO"`
+	NetTraffic NetTraffic `json:"netTraffic"`
+	PublicIP   PublicIP   `json:"publicIP"`
+	AppStats   AppStats   `json:"appStats"`
+}
+
+// UptimeDuration returns the server's uptime.
+func (s ServerStatus) UptimeDuration() time.Duration {
+	return time.Duration(s.Uptime) * time.Second
+}
+
+// UptimeString returns the uptime the way the panel shows it, e.g. "3d 4h"
+// or "25m".
+func (s ServerStatus) UptimeString() string {
+	return formatUptime(s.UptimeDuration())
+}
+
+var uptimeUnits = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// formatUptime shows the largest unit of d and the next one if non-zero.
+func formatUptime(d time.Duration) string {
+	last := len(uptimeUnits) - 1
+	for i, u := range uptimeUnits {
+		if d < u.size && i < last {
+			continue
+		}
+		n := d / u.size
+		out := fmt.Sprintf("%d%s", n, u.suffix)
+		if i < last {
+			next := uptimeUnits[i+1]
+			if m := (d - n*u.size) / next.size; m > 0 {
+				out += fmt.Sprintf(" %d%s", m, next.suffix)
+			}
+		}
+		return out
+	}
+	return ""
+}
+
 type ServerStatusResponse struct {
-	Success bool   `json:"success"`
-	Msg     string `json:"msg"`
-	Obj     *struct {
-		Cpu         float64 `json:"cpu"`
-		CpuCores    int     `json:"cpuCores"`
-		CpuSpeedMhz float64 `json:"cpuSpeedMhz"`
-		Mem         struct {
-			Current uint64 `json:"current"`
-			Total   uint64 `json:"total"`
-		} `json:"mem"`
-		Swap struct {
-			Current uint64 `json:"current"`
-			Total   uint64 `json:"total"`
-		} `json:"swap"`
-		Disk struct {
-			Current uint64 `json:"current"`
-			Total   uint64 `json:"total"`
-		} `json:"disk"`
-		Xray struct {
-			State    ProcessState `json:"state"`
-			ErrorMsg string       `json:"errorMsg"`
-			Version  string       `json:"version"`
-		} `json:"xray"`
-		Uptime   uint64    `json:"uptime"`
-		Loads    []float64 `json:"loads"`
-		TcpCount int       `json:"tcpCount"`
-		UdpCount int       `json:"udpCount"`
-		NetIO    struct {
-			Up   uint64 `json:"up"`
-			Down uint64 `json:"down"`
-		} `json:"netIO"`
-		NetTraffic struct {
-			Sent uint64 `json:"sent"`
-			Recv uint64 `json:"recv"`
-		} `json:"netTraffic"`
-		PublicIP struct {
-			IPv4 string `json:"ipv4"`
-			IPv6 string `json:"ipv6"`
-		} `json:"publicIP"`
-		AppStats struct {
-			Threads uint32 `json:"threads"`
-			Mem     uint64 `json:"mem"`
-			Uptime  uint64 `json:"uptime"`
-		} `json:"appStats"`
-	} `json:"obj"`
-}
-
-func (c *Client) ServerStatus(ctx context.Context) (*ServerStatusResponse, error) {
+	Success bool          `json:"success"`
+	Msg     string        `json:"msg"`
+	Obj     *ServerStatus `json:"obj"`
+}
+
+func (c *Client) ServerStatus(ctx context.Context) (*ServerStatus, error) {
 	resp := &ServerStatusResponse{}
 	err := c.Do(ctx, http.MethodPost, "/server/status", nil, resp)
-	return resp, err
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf(resp.Msg)
+	}
+	if resp.Obj == nil {
+		return nil, fmt.Errorf("empty server status")
+	}
+	return resp.Obj, nil
 }
@@ -0,0 +1,66 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestServerStatusDecoding(t *testing.T) {
+	data := `{"success":true,"msg":"","obj":{"cpu":12.5,"cpuCores":2,"logicalPro":4,"cpuSpeedMhz":2400,
+		"mem":{"current":536870912,"total":2147483648},"swap":{"current":0,"total":0},
+		"disk":{"current":10737418240,"total":42949672960},
+		"xray":{"state":"running","errorMsg":"","version":"25.1.30"},
+		"uptime":273900,"loads":[0.1,0.2,0.3],"tcpCount":42,"udpCount":7,
+		"netIO":{"up":1024,"down":2048},"netTraffic":{"sent":1073741824,"recv":2147483648},
+		"publicIP":{"ipv4":"89.169.53.31","ipv6":"N/A"},
+		"appStats":{"threads":12,"mem":41943040,"uptime":3600}}}`
+	var resp ServerStatusResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	status := resp.Obj
+	if status.Mem.Percent() != 25 || status.Disk.Free() != 30*GiB || status.Swap.Percent() != 0 {
+		t.Errorf("Unexpected usage %+v %+v %+v", status.Mem, status.Disk, status.Swap)
+	}
+	if status.Xray.State != Running || status.NetTraffic.Recv != 2*GiB {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if status.UptimeString() != "3d 4h" || status.AppStats.UptimeDuration() != time.Hour {
+		t.Errorf("Unexpected uptime %q, %v", status.UptimeString(), status.AppStats.UptimeDuration())
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{59 * time.Second, "59s"},
+		{25*time.Minute + 3*time.Second, "25m 3s"},
+		{2 * time.Hour, "2h"},
+		{49*time.Hour + 30*time.Minute, "2d 1h"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.d); got != tt.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
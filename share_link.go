@@ -0,0 +1,447 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultRemarkModel is the panel's default PanelSettings.RemarkModel.
+const DefaultRemarkModel = "-ieo"
+
+// ShareLinkOptions control how share links are built.
+type ShareLinkOptions struct {
+	// Public address of the server. If empty, one link is built per
+	// ExternalProxy entry of the inbound, or the inbound's listen address is
+	// used when it has none.
+	Address string
+	// PanelSettings.RemarkModel, DefaultRemarkModel if empty.
+	RemarkModel string
+}
+
+// shareTarget is one address a link points to.
+type shareTarget struct {
+	address string
+	port    int
+	// Security after applying ExternalProxy.ForceTls.
+	security string
+	// Remark of the external proxy.
+	extra string
+}
+
+// Remark builds a link remark the way the panel does: the first character of
+// model is the separator, the rest orders the inbound remark (i), client
+// email (e) and external proxy remark (o). Empty parts are left out.
+func Remark(model, inboundRemark, email, extra string) string {
+	if model == "" {
+		model = DefaultRemarkModel
+	}
+	sep := model[:1]
+	parts := map[byte]string{'i': inboundRemark, 'e': email, 'o': extra}
+	var out []string
+	for i := 1; i < len(model); i++ {
+		if v := parts[model[i]]; v != "" {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// ShareLinks builds the vless://, vmess://, trojan:// or ss:// links the
+// panel shows for a client of the inbound.
+func (i Inbound) ShareLinks(client InboundClient, opts ShareLinkOptions) ([]string, error) {
+	stream, err := i.GetStreamSettings()
+	if err != nil && i.StreamSettings != "" {
+		return nil, fmt.Errorf("invalid stream settings: %w", err)
+	}
+
+	targets, err := i.shareTargets(stream, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]string, 0, len(targets))
+	for _, t := range targets {
+		remark := Remark(opts.RemarkModel, i.Remark, client.Email, t.extra)
+		var link string
+		switch i.Protocol {
+		case "vless":
+			link, err = i.vlessLink(client, stream, t, remark)
+		case "vmess":
+			link, err = vmessLink(client, stream, t, remark)
+		case "trojan":
+			link, err = trojanLink(client, stream, t, remark)
+		case "shadowsocks":
+			link, err = i.shadowsocksLink(client, stream, t, remark)
+		default:
+			err = fmt.Errorf("share links are not supported for protocol %q", i.Protocol)
+		}
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// shareTargets lists the addresses links of the inbound point to.
+func (i Inbound) shareTargets(stream InboundStreamSettings, opts ShareLinkOptions) ([]shareTarget, error) {
+	var targets []shareTarget
+	switch {
+	case opts.Address != "":
+		targets = append(targets, shareTarget{address: opts.Address, port: i.Port, security: stream.Security})
+	case len(stream.ExternalProxy) > 0:
+		for _, ep := range stream.ExternalProxy {
+			security := stream.Security
+			switch ep.ForceTls {
+			case "tls":
+				security = "tls"
+			case "none":
+				security = "none"
+			}
+			targets = append(targets, shareTarget{address: ep.Dest, port: ep.Port, security: security, extra: ep.Remark})
+		}
+	case i.Listen != "" && i.Listen != "0.0.0.0" && i.Listen != "::":
+		targets = append(targets, shareTarget{address: i.Listen, port: i.Port, security: stream.Security})
+	default:
+		return nil, errors.New("no public address: set ShareLinkOptions.Address")
+	}
+	return targets, nil
+}
+
+// ShareLink returns the first link of ShareLinks.
+func (i Inbound) ShareLink(client InboundClient, opts ShareLinkOptions) (string, error) {
+	links, err := i.ShareLinks(client, opts)
+	if err != nil {
+		return "", err
+	}
+	return links[0], nil
+}
+
+// GetClientShareLinks builds the share links of the client with the given
+// email using the panel's remark model. Inbounds without external proxies
+// get the panel's host name as address, like in the panel UI.
+func (c *Client) GetClientShareLinks(ctx context.Context, email string) ([]string, error) {
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inbound, client, err := findClient(inbounds.Obj, email)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := c.shareLinkOptions(ctx, *inbound)
+	if err != nil {
+		return nil, err
+	}
+	return inbound.ShareLinks(*client, opts)
+}
+
+// shareLinkOptions returns the panel's remark model, and the panel's host
+// name as address for inbounds without external proxies.
+func (c *Client) shareLinkOptions(ctx context.Context, inbound Inbound) (ShareLinkOptions, error) {
+	settings, err := c.GetPanelSettings(ctx)
+	if err != nil {
+		return ShareLinkOptions{}, err
+	}
+	opts := ShareLinkOptions{RemarkModel: settings.Obj.RemarkModel}
+	if stream, err := inbound.GetStreamSettings(); err != nil || len(stream.ExternalProxy) == 0 {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return ShareLinkOptions{}, err
+		}
+		opts.Address = u.Hostname()
+	}
+	return opts, nil
+}
+
+// hostHeader returns the Host header of a transport.
+func hostHeader(host string, headers map[string]string) string {
+	if host != "" {
+		return host
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, "host") {
+			return v
+		}
+	}
+	return ""
+}
+
+// transportParams adds the query parameters describing the transport and
+// security shared by vless, trojan and ss links.
+func transportParams(params url.Values, stream InboundStreamSettings, security string) {
+	network := stream.Network
+	if network == "" {
+		network = "tcp"
+	}
+	params.Set("type", network)
+	switch network {
+	case "tcp":
+		if tcp := stream.TcpSettings; tcp != nil && tcp.Header.Type == "http" {
+			params.Set("headerType", "http")
+			if req := tcp.Header.Request; req != nil {
+				if len(req.Path) > 0 {
+					params.Set("path", req.Path[0])
+				}
+				for k, v := range req.Headers {
+					if strings.EqualFold(k, "host") {
+						params.Set("host", strings.Join(v, ","))
+					}
+				}
+			}
+		}
+	case "kcp":
+		if kcp := stream.KcpSettings; kcp != nil {
+			params.Set("headerType", kcp.Header.Type)
+			params.Set("seed", kcp.Seed)
+		}
+	case "ws":
+		if ws := stream.WsSettings; ws != nil {
+			params.Set("path", ws.Path)
+			params.Set("host", hostHeader(ws.Host, ws.Headers))
+		}
+	case "grpc":
+		if grpc := stream.GrpcSettings; grpc != nil {
+			params.Set("serviceName", grpc.ServiceName)
+			params.Set("authority", grpc.Authority)
+			if grpc.MultiMode {
+				params.Set("mode", "multi")
+			}
+		}
+	case "httpupgrade":
+		if hu := stream.HttpUpgradeSettings; hu != nil {
+			params.Set("path", hu.Path)
+			params.Set("host", hostHeader(hu.Host, hu.Headers))
+		}
+	case "xhttp":
+		if xh := stream.XhttpSettings; xh != nil {
+			params.Set("path", xh.Path)
+			params.Set("host", hostHeader(xh.Host, xh.Headers))
+			params.Set("mode", xh.Mode)
+		}
+	}
+
+	switch security {
+	case "tls":
+		params.Set("security", "tls")
+		if tls := stream.TlsSettings; tls != nil {
+			params.Set("fp", tls.Settings.Fingerprint)
+			if len(tls.Alpn) > 0 {
+				params.Set("alpn", strings.Join(tls.Alpn, ","))
+			}
+			if tls.Settings.AllowInsecure {
+				params.Set("allowInsecure", "1")
+			}
+			if tls.ServerName != "" {
+				params.Set("sni", tls.ServerName)
+			}
+			if tls.Settings.EchConfigList != "" {
+				params.Set("ech", tls.Settings.EchConfigList)
+			}
+		}
+	case "reality":
+		params.Set("security", "reality")
+		if reality := stream.RealitySettings; reality != nil {
+			params.Set("pbk", reality.Settings.PublicKey)
+			params.Set("fp", reality.Settings.Fingerprint)
+			if len(reality.ServerNames) > 0 {
+				params.Set("sni", reality.ServerNames[0])
+			}
+			if len(reality.ShortIds) > 0 {
+				params.Set("sid", reality.ShortIds[0])
+			}
+			if reality.Settings.SpiderX != "" {
+				params.Set("spx", reality.Settings.SpiderX)
+			}
+		}
+	default:
+		params.Set("security", "none")
+	}
+
+	// Drop parameters the inbound left empty, the panel does the same.
+	for k, v := range params {
+		if len(v) == 1 && v[0] == "" {
+			params.Del(k)
+		}
+	}
+}
+
+func buildLink(scheme string, user *url.Userinfo, t shareTarget, params url.Values, remark string) string {
+	u := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     net.JoinHostPort(t.address, strconv.Itoa(t.port)),
+		RawQuery: params.Encode(),
+		Fragment: remark,
+	}
+	return u.String()
+}
+
+func (i Inbound) vlessLink(client InboundClient, stream InboundStreamSettings, t shareTarget, remark string) (string, error) {
+	if client.ID == "" {
+		return "", errors.New("client has no UUID")
+	}
+	var settings struct {
+		Encryption string `json:"encryption"`
+	}
+	_ = json.Unmarshal([]byte(i.Settings), &settings)
+	if settings.Encryption == "" {
+		settings.Encryption = "none"
+	}
+
+	params := url.Values{}
+	params.Set("encryption", settings.Encryption)
+	transportParams(params, stream, t.security)
+	network := params.Get("type")
+	if client.Flow != "" && network == "tcp" && (t.security == "tls" || t.security == "reality") {
+		params.Set("flow", client.Flow)
+	}
+	return buildLink("vless", url.User(client.ID), t, params, remark), nil
+}
+
+func trojanLink(client InboundClient, stream InboundStreamSettings, t shareTarget, remark string) (string, error) {
+	if client.Password == "" {
+		return "", errors.New("client has no password")
+	}
+	params := url.Values{}
+	transportParams(params, stream, t.security)
+	return buildLink("trojan", url.User(client.Password), t, params, remark), nil
+}
+
+func (i Inbound) shadowsocksLink(client InboundClient, stream InboundStreamSettings, t shareTarget, remark string) (string, error) {
+	method, password, err := i.shadowsocksCredentials(client)
+	if err != nil {
+		return "", err
+	}
+	userinfo := base64.RawURLEncoding.EncodeToString([]byte(method + ":" + password))
+
+	params := url.Values{}
+	transportParams(params, stream, t.security)
+	if params.Get("security") == "none" {
+		params.Del("security")
+	}
+	return buildLink("ss", url.User(userinfo), t, params, remark), nil
+}
+
+// shadowsocksCredentials returns the method and the password a client
+// connects with. Shadowsocks-2022 passwords carry the server key before the
+// user key, legacy multi-user inbounds only the user password.
+func (i Inbound) shadowsocksCredentials(client InboundClient) (method, password string, err error) {
+	settings, err := i.GetShadowsocksSettings()
+	if err != nil {
+		return "", "", fmt.Errorf("invalid shadowsocks settings: %w", err)
+	}
+	var passwords []string
+	if ss2022KeySize(settings.Method) > 0 {
+		passwords = append(passwords, settings.Password)
+	}
+	if client.Password != "" {
+		passwords = append(passwords, client.Password)
+	}
+	if len(passwords) == 0 {
+		return "", "", errors.New("client has no password")
+	}
+	return settings.Method, strings.Join(passwords, ":"), nil
+}
+
+// vmessLink builds the base64 encoded JSON link, with the same keys as the
+// panel.
+func vmessLink(client InboundClient, stream InboundStreamSettings, t shareTarget, remark string) (string, error) {
+	if client.ID == "" {
+		return "", errors.New("client has no UUID")
+	}
+	security := client.Security
+	if security == "" {
+		security = "auto"
+	}
+	network := stream.Network
+	if network == "" {
+		network = "tcp"
+	}
+	tls := "none"
+	if t.security == "tls" {
+		tls = "tls"
+	}
+	obj := map[string]interface{}{
+		"v":    "2",
+		"ps":   remark,
+		"add":  t.address,
+		"port": t.port,
+		"id":   client.ID,
+		"scy":  security,
+		"net":  network,
+		"type": "none",
+		"tls":  tls,
+	}
+
+	// Reuse the transport parameters of the URL based links and map them
+	// onto the vmess keys.
+	params := url.Values{}
+	transportParams(params, stream, t.security)
+	switch network {
+	case "tcp":
+		if params.Get("headerType") == "http" {
+			obj["type"] = "http"
+		}
+		obj["path"] = params.Get("path")
+		obj["host"] = params.Get("host")
+	case "kcp":
+		obj["type"] = params.Get("headerType")
+		obj["path"] = params.Get("seed")
+	case "ws", "httpupgrade":
+		obj["path"] = params.Get("path")
+		obj["host"] = params.Get("host")
+	case "grpc":
+		obj["path"] = params.Get("serviceName")
+		obj["authority"] = params.Get("authority")
+		if params.Get("mode") == "multi" {
+			obj["type"] = "multi"
+		}
+	case "xhttp":
+		obj["path"] = params.Get("path")
+		obj["host"] = params.Get("host")
+		obj["type"] = params.Get("mode")
+	}
+	if tls == "tls" {
+		obj["sni"] = params.Get("sni")
+		obj["fp"] = params.Get("fp")
+		obj["alpn"] = params.Get("alpn")
+		if params.Get("allowInsecure") == "1" {
+			obj["allowInsecure"] = true
+		}
+	}
+	for k, v := range obj {
+		if v == "" {
+			delete(obj, k)
+		}
+	}
+
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
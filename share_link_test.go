@@ -0,0 +1,161 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var testRealityInbound = Inbound{
+	ID:       1,
+	Remark:   "de",
+	Port:     443,
+	Protocol: "vless",
+	Settings: `{"clients":[{"id":"8e72473d-3c52-4153-b5ba-3b06035d0ad1","email":"alice","flow":"xtls-rprx-vision"}],"decryption":"none"}`,
+	StreamSettings: `{
+		"network": "tcp",
+		"security": "reality",
+		"externalProxy": [],
+		"tcpSettings": {"acceptProxyProtocol": false, "header": {"type": "none"}},
+		"realitySettings": {
+			"serverNames": ["rt.com", "www.rt.com"],
+			"shortIds": ["82c54a0dbca8", "ab"],
+			"settings": {"publicKey": "QpIeLuq1OYR1dSWituaXb0c8h4iZtkFPIjKxLKiyC3o", "fingerprint": "chrome", "spiderX": "/"}
+		}
+	}`,
+}
+
+func TestRemark(t *testing.T) {
+	tests := []struct {
+		model, want string
+	}{
+		{"", "de-alice-cdn"},
+		{"_ei", "alice_de"},
+		{"|o", "cdn"},
+	}
+	for _, tt := range tests {
+		if got := Remark(tt.model, "de", "alice", "cdn"); got != tt.want {
+			t.Errorf("Remark(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+	if got := Remark("", "de", "alice", ""); got != "de-alice" {
+		t.Errorf("Expected empty parts to be skipped, got %q", got)
+	}
+}
+
+func TestVlessRealityShareLink(t *testing.T) {
+	clients, _ := testRealityInbound.Clients()
+	link, err := testRealityInbound.ShareLink(clients[0], ShareLinkOptions{Address: "89.169.53.31"})
+	if err != nil {
+		t.Fatalf("ShareLink() error = %v", err)
+	}
+	want := "vless://8e72473d-3c52-4153-b5ba-3b06035d0ad1@89.169.53.31:443?encryption=none&flow=xtls-rprx-vision&fp=chrome&pbk=QpIeLuq1OYR1dSWituaXb0c8h4iZtkFPIjKxLKiyC3o&security=reality&sid=82c54a0dbca8&sni=rt.com&spx=%2F&type=tcp#de-alice"
+	if link != want {
+		t.Errorf("Unexpected link:\n got %s\nwant %s", link, want)
+	}
+}
+
+func TestShareLinksExternalProxy(t *testing.T) {
+	inbound := Inbound{
+		Remark:   "ws",
+		Port:     8080,
+		Protocol: "trojan",
+		StreamSettings: `{
+			"network": "ws",
+			"security": "none",
+			"externalProxy": [
+				{"forceTls": "tls", "dest": "cdn.example.com", "port": 443, "remark": "cdn"},
+				{"forceTls": "same", "dest": "1.2.3.4", "port": 8080, "remark": ""}
+			],
+			"wsSettings": {"path": "/ws", "host": "", "headers": {"Host": "example.com"}},
+			"tlsSettings": {"serverName": "example.com", "alpn": ["h2", "http/1.1"], "settings": {"fingerprint": "chrome"}}
+		}`,
+	}
+	links, err := inbound.ShareLinks(InboundClient{Email: "bob", Password: "secret"}, ShareLinkOptions{})
+	if err != nil {
+		t.Fatalf("ShareLinks() error = %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %d", len(links))
+	}
+	want := "trojan://secret@cdn.example.com:443?alpn=h2%2Chttp%2F1.1&fp=chrome&host=example.com&path=%2Fws&security=tls&sni=example.com&type=ws#ws-bob-cdn"
+	if links[0] != want {
+		t.Errorf("Unexpected link:\n got %s\nwant %s", links[0], want)
+	}
+	if !strings.Contains(links[1], "security=none") || !strings.HasSuffix(links[1], "#ws-bob") {
+		t.Errorf("Unexpected second link %s", links[1])
+	}
+}
+
+func TestVmessShareLink(t *testing.T) {
+	inbound := Inbound{
+		Remark:         "vm",
+		Port:           10086,
+		Protocol:       "vmess",
+		StreamSettings: `{"network":"grpc","security":"tls","grpcSettings":{"serviceName":"svc","multiMode":true},"tlsSettings":{"serverName":"example.com","settings":{"fingerprint":"firefox"}}}`,
+	}
+	link, err := inbound.ShareLink(InboundClient{ID: "uuid", Email: "carol"}, ShareLinkOptions{Address: "example.com", RemarkModel: "-e"})
+	if err != nil {
+		t.Fatalf("ShareLink() error = %v", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(link, "vmess://"))
+	if err != nil {
+		t.Fatalf("Link is not base64: %v", err)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatalf("Link is not JSON: %v", err)
+	}
+	want := map[string]interface{}{
+		"v": "2", "ps": "carol", "add": "example.com", "port": float64(10086), "id": "uuid",
+		"scy": "auto", "net": "grpc", "type": "multi", "tls": "tls", "path": "svc",
+		"sni": "example.com", "fp": "firefox",
+	}
+	for k, v := range want {
+		if obj[k] != v {
+			t.Errorf("Key %s: expected %v, got %v", k, v, obj[k])
+		}
+	}
+}
+
+func TestShadowsocksShareLink(t *testing.T) {
+	inbound := Inbound{
+		Remark:         "ss",
+		Port:           8388,
+		Protocol:       "shadowsocks",
+		Settings:       `{"method":"2022-blake3-aes-128-gcm","password":"c2VydmVyLWtleS0xNi1ieXQ=","network":"tcp,udp"}`,
+		StreamSettings: `{"network":"tcp","security":"none"}`,
+	}
+	link, err := inbound.ShareLink(InboundClient{Email: "dave", Password: "dXNlci1rZXktMTYtYnl0ZXM="}, ShareLinkOptions{Address: "1.2.3.4"})
+	if err != nil {
+		t.Fatalf("ShareLink() error = %v", err)
+	}
+	userinfo := strings.TrimPrefix(strings.SplitN(link, "@", 2)[0], "ss://")
+	decoded, err := base64.RawURLEncoding.DecodeString(userinfo)
+	if err != nil {
+		t.Fatalf("Userinfo is not base64: %v", err)
+	}
+	if string(decoded) != "2022-blake3-aes-128-gcm:c2VydmVyLWtleS0xNi1ieXQ=:dXNlci1rZXktMTYtYnl0ZXM=" {
+		t.Errorf("Unexpected userinfo %q", decoded)
+	}
+	if !strings.HasSuffix(link, "@1.2.3.4:8388?type=tcp#ss-dave") {
+		t.Errorf("Unexpected link %s", link)
+	}
+}
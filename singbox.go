@@ -0,0 +1,412 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SingBoxOutbound is a sing-box outbound. Server outbounds use the
+// connection fields, selector and urltest outbounds the group fields.
+type SingBoxOutbound struct {
+	Type       string `json:"type"`
+	Tag        string `json:"tag"`
+	Server     string `json:"server,omitempty"`
+	ServerPort int    `json:"server_port,omitempty"`
+	// vless and vmess
+	UUID     string `json:"uuid,omitempty"`
+	Flow     string `json:"flow,omitempty"`
+	Security string `json:"security,omitempty"`
+	AlterID  int    `json:"alter_id,omitempty"`
+	// trojan and shadowsocks
+	Method    string            `json:"method,omitempty"`
+	Password  string            `json:"password,omitempty"`
+	TLS       *SingBoxTLS       `json:"tls,omitempty"`
+	Transport *SingBoxTransport `json:"transport,omitempty"`
+	Multiplex *SingBoxMultiplex `json:"multiplex,omitempty"`
+
+	// selector and urltest
+	Outbounds []string `json:"outbounds,omitempty"`
+	Default   string   `json:"default,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Interval  string   `json:"interval,omitempty"`
+	Tolerance int      `json:"tolerance,omitempty"`
+}
+
+// SingBoxTLS is the tls object of a sing-box outbound.
+type SingBoxTLS struct {
+	Enabled    bool            `json:"enabled"`
+	ServerName string          `json:"server_name,omitempty"`
+	Insecure   bool            `json:"insecure,omitempty"`
+	ALPN       []string        `json:"alpn,omitempty"`
+	UTLS       *SingBoxUTLS    `json:"utls,omitempty"`
+	Reality    *SingBoxReality `json:"reality,omitempty"`
+}
+
+// SingBoxUTLS selects the uTLS client fingerprint.
+type SingBoxUTLS struct {
+	Enabled     bool   `json:"enabled"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// SingBoxReality holds the Reality client settings.
+type SingBoxReality struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key"`
+	ShortID   string `json:"short_id,omitempty"`
+}
+
+// SingBoxTransport is the V2Ray transport of a sing-box outbound.
+type SingBoxTransport struct {
+	// "ws", "grpc" or "httpupgrade".
+	Type                string            `json:"type"`
+	Host                string            `json:"host,omitempty"`
+	Path                string            `json:"path,omitempty"`
+	Headers             map[string]string `json:"headers,omitempty"`
+	MaxEarlyData        int               `json:"max_early_data,omitempty"`
+	EarlyDataHeaderName string            `json:"early_data_header_name,omitempty"`
+	ServiceName         string            `json:"service_name,omitempty"`
+}
+
+// SingBoxMultiplex enables sing-box multiplexing. The server must support
+// sing-mux, which plain Xray doesn't.
+type SingBoxMultiplex struct {
+	Enabled bool `json:"enabled"`
+	// "smux", "yamux" or "h2mux".
+	Protocol       string `json:"protocol,omitempty"`
+	MaxConnections int    `json:"max_connections,omitempty"`
+	MinStreams     int    `json:"min_streams,omitempty"`
+	MaxStreams     int    `json:"max_streams,omitempty"`
+	Padding        bool   `json:"padding,omitempty"`
+}
+
+// SingBoxConfig is a sing-box client profile.
+type SingBoxConfig struct {
+	Log       map[string]interface{}   `json:"log,omitempty"`
+	DNS       map[string]interface{}   `json:"dns,omitempty"`
+	Inbounds  []map[string]interface{} `json:"inbounds,omitempty"`
+	Outbounds []SingBoxOutbound        `json:"outbounds"`
+	Route     map[string]interface{}   `json:"route,omitempty"`
+}
+
+// SingBoxRuleSet is a remote rule set routed to one outbound.
+type SingBoxRuleSet struct {
+	Tag string
+	URL string
+	// "binary" or "source", binary if empty.
+	Format string
+	// Outbound tag of matching connections, or "reject" to block them.
+	Outbound string
+}
+
+// SingBoxOptions control the profile built by SingBoxProfile.
+type SingBoxOptions struct {
+	// Tags of the selector and urltest outbounds, "proxy" and "auto" if
+	// empty.
+	SelectorTag string
+	URLTestTag  string
+	// Health check of the urltest outbound. Defaults to
+	// https://www.gstatic.com/generate_204 every 5 minutes.
+	TestURL      string
+	TestInterval time.Duration
+	// DNS over HTTPS server queried through the proxy, 1.1.1.1 if empty.
+	RemoteDNS string
+	// Listen port of a mixed HTTP/SOCKS inbound. If zero the profile uses a
+	// tun inbound, as mobile apps expect.
+	MixedPort int
+	// Applied to every server outbound if not nil.
+	Multiplex *SingBoxMultiplex
+	// Rule sets, matched in order before the final proxy route.
+	RuleSets []SingBoxRuleSet
+}
+
+// singBoxFingerprints are the uTLS fingerprints sing-box knows.
+var singBoxFingerprints = map[string]bool{
+	"chrome": true, "firefox": true, "edge": true, "safari": true, "360": true,
+	"qq": true, "ios": true, "android": true, "random": true, "randomized": true,
+}
+
+// NewSingBoxOutbound converts a vless, vmess, trojan or shadowsocks outbound
+// into a sing-box outbound. Settings sing-box can't express, such as Xray
+// mux or the kcp and xhttp transports, are an error rather than being
+// dropped.
+func NewSingBoxOutbound(o XrayOutbound) (*SingBoxOutbound, error) {
+	p, err := decodeProxyOutbound(o)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, _ := o.Mux["enabled"].(bool); enabled {
+		return nil, errors.New("sing-box multiplex is not compatible with Xray mux")
+	}
+
+	out := &SingBoxOutbound{
+		Type:       p.Protocol,
+		Tag:        proxyName(p),
+		Server:     p.Address,
+		ServerPort: p.Port,
+	}
+	switch p.Protocol {
+	case "vless":
+		if p.Encryption != "" && p.Encryption != "none" {
+			return nil, fmt.Errorf("VLESS encryption %q is not supported by sing-box", p.Encryption)
+		}
+		out.UUID, out.Flow = p.ID, p.Flow
+	case "vmess":
+		out.UUID, out.AlterID = p.ID, p.AlterID
+		out.Security = valueOr(p.Cipher, "auto")
+	case "trojan":
+		out.Password = p.Password
+	case "shadowsocks":
+		method, ok := ssCiphers[p.Method]
+		if !ok {
+			return nil, fmt.Errorf("shadowsocks method %q is not supported by sing-box", p.Method)
+		}
+		out.Method, out.Password = method, p.Password
+	}
+
+	switch p.Security {
+	case "none":
+	case "tls", "reality":
+		if p.Protocol == "shadowsocks" {
+			return nil, errors.New("shadowsocks over TLS is not supported by sing-box")
+		}
+		out.TLS = &SingBoxTLS{
+			Enabled:    true,
+			ServerName: p.ServerName,
+			Insecure:   p.AllowInsecure,
+			ALPN:       p.Alpn,
+		}
+		fingerprint := p.Fingerprint
+		if p.Security == "reality" {
+			// Reality needs uTLS in sing-box.
+			fingerprint = valueOr(fingerprint, "chrome")
+			out.TLS.Reality = &SingBoxReality{Enabled: true, PublicKey: p.PublicKey, ShortID: p.ShortID}
+		}
+		if fingerprint != "" {
+			if !singBoxFingerprints[fingerprint] {
+				return nil, fmt.Errorf("uTLS fingerprint %q is not supported by sing-box", fingerprint)
+			}
+			out.TLS.UTLS = &SingBoxUTLS{Enabled: true, Fingerprint: fingerprint}
+		}
+	default:
+		return nil, fmt.Errorf("security %q is not supported by sing-box", p.Security)
+	}
+
+	switch p.Network {
+	case "tcp":
+		if p.HeaderType != "" {
+			return nil, errors.New("HTTP header obfuscation is not supported by sing-box")
+		}
+	case "ws":
+		if p.Protocol == "shadowsocks" {
+			return nil, errors.New("shadowsocks with a transport is not supported by sing-box")
+		}
+		out.Transport = &SingBoxTransport{Type: "ws"}
+		if err := singBoxWsPath(out.Transport, p.Path); err != nil {
+			return nil, err
+		}
+		if p.Host != "" {
+			out.Transport.Headers = map[string]string{"Host": p.Host}
+		}
+	case "httpupgrade":
+		if p.Protocol == "shadowsocks" {
+			return nil, errors.New("shadowsocks with a transport is not supported by sing-box")
+		}
+		out.Transport = &SingBoxTransport{Type: "httpupgrade", Host: p.Host, Path: p.Path}
+	case "grpc":
+		if p.Protocol == "shadowsocks" {
+			return nil, errors.New("shadowsocks with a transport is not supported by sing-box")
+		}
+		// sing-box has no option for the :authority of gRPC requests.
+		if p.Host != "" {
+			return nil, errors.New("gRPC authority is not supported by sing-box")
+		}
+		out.Transport = &SingBoxTransport{Type: "grpc", ServiceName: p.ServiceName}
+	default:
+		return nil, fmt.Errorf("transport %q is not supported by sing-box", p.Network)
+	}
+	return out, nil
+}
+
+// singBoxWsPath moves the "?ed=2048" early data parameter of a WebSocket
+// path into the sing-box early data options.
+func singBoxWsPath(t *SingBoxTransport, path string) error {
+	path, query, found := strings.Cut(path, "?")
+	t.Path = path
+	if !found {
+		return nil
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return fmt.Errorf("invalid WebSocket path query %q", query)
+	}
+	ed := params.Get("ed")
+	if ed == "" {
+		t.Path += "?" + query
+		return nil
+	}
+	if t.MaxEarlyData, err = strconv.Atoi(ed); err != nil {
+		return fmt.Errorf("invalid WebSocket early data %q", ed)
+	}
+	t.EarlyDataHeaderName = "Sec-WebSocket-Protocol"
+	params.Del("ed")
+	if len(params) > 0 {
+		t.Path += "?" + params.Encode()
+	}
+	return nil
+}
+
+// SingBoxOutbounds converts outbounds, applying multiplex if not nil. Tags
+// are made unique. Outbounds sing-box can't represent are returned in
+// skipped.
+func SingBoxOutbounds(outbounds []XrayOutbound, multiplex *SingBoxMultiplex) ([]SingBoxOutbound, []SkippedOutbound) {
+	return singBoxOutbounds(outbounds, multiplex, map[string]bool{})
+}
+
+func singBoxOutbounds(outbounds []XrayOutbound, multiplex *SingBoxMultiplex, taken map[string]bool) ([]SingBoxOutbound, []SkippedOutbound) {
+	var converted []SingBoxOutbound
+	var skipped []SkippedOutbound
+	for _, o := range outbounds {
+		out, err := NewSingBoxOutbound(o)
+		if err == nil && multiplex != nil {
+			if out.Flow != "" {
+				err = fmt.Errorf("multiplex can't be used with flow %q", out.Flow)
+			}
+			out.Multiplex = multiplex
+		}
+		if err != nil {
+			skipped = append(skipped, SkippedOutbound{Tag: o.Tag, Err: err})
+			continue
+		}
+		out.Tag = uniqueName(out.Tag, taken)
+		converted = append(converted, *out)
+	}
+	return converted, skipped
+}
+
+// SingBoxProfile builds a complete sing-box client profile from outbounds:
+// DNS through the proxy, route rules for private addresses and the given
+// rule sets, and a selector with a urltest group across all servers.
+// Outbounds sing-box can't represent are returned in skipped, it is an error
+// if none are left.
+func SingBoxProfile(outbounds []XrayOutbound, opts SingBoxOptions) (*SingBoxConfig, []SkippedOutbound, error) {
+	opts.SelectorTag = valueOr(opts.SelectorTag, "proxy")
+	opts.URLTestTag = valueOr(opts.URLTestTag, "auto")
+	opts.TestURL = valueOr(opts.TestURL, "https://www.gstatic.com/generate_204")
+	if opts.TestInterval == 0 {
+		opts.TestInterval = 5 * time.Minute
+	}
+	opts.RemoteDNS = valueOr(opts.RemoteDNS, "1.1.1.1")
+
+	taken := map[string]bool{"direct": true, opts.SelectorTag: true, opts.URLTestTag: true}
+	servers, skipped := singBoxOutbounds(outbounds, opts.Multiplex, taken)
+	if len(servers) == 0 {
+		return nil, skipped, errors.New("no outbounds can be represented in sing-box")
+	}
+	tags := make([]string, len(servers))
+	for i, s := range servers {
+		tags[i] = s.Tag
+	}
+
+	config := &SingBoxConfig{
+		Log: map[string]interface{}{"level": "info", "timestamp": true},
+		DNS: map[string]interface{}{
+			"servers": []map[string]interface{}{
+				{"type": "https", "tag": "remote", "server": opts.RemoteDNS, "detour": opts.SelectorTag},
+				{"type": "local", "tag": "local"},
+			},
+			"final": "remote",
+		},
+	}
+	if opts.MixedPort != 0 {
+		config.Inbounds = []map[string]interface{}{
+			{"type": "mixed", "tag": "mixed-in", "listen": "127.0.0.1", "listen_port": opts.MixedPort},
+		}
+	} else {
+		config.Inbounds = []map[string]interface{}{
+			{
+				"type":         "tun",
+				"tag":          "tun-in",
+				"address":      []string{"172.19.0.1/30", "fdfe:dcba:9876::1/126"},
+				"auto_route":   true,
+				"strict_route": true,
+			},
+		}
+	}
+
+	config.Outbounds = append([]SingBoxOutbound{
+		{
+			Type:      "selector",
+			Tag:       opts.SelectorTag,
+			Outbounds: append([]string{opts.URLTestTag}, tags...),
+			Default:   opts.URLTestTag,
+		},
+		{
+			Type:      "urltest",
+			Tag:       opts.URLTestTag,
+			Outbounds: tags,
+			URL:       opts.TestURL,
+			Interval:  opts.TestInterval.String(),
+			Tolerance: 50,
+		},
+	}, servers...)
+	config.Outbounds = append(config.Outbounds, SingBoxOutbound{Type: "direct", Tag: "direct"})
+
+	rules := []map[string]interface{}{
+		{"action": "sniff"},
+		{"protocol": "dns", "action": "hijack-dns"},
+		{"ip_is_private": true, "outbound": "direct"},
+	}
+	var ruleSets []map[string]interface{}
+	for _, rs := range opts.RuleSets {
+		if rs.Tag == "" || rs.URL == "" || rs.Outbound == "" {
+			return nil, skipped, fmt.Errorf("rule set %q needs a tag, URL and outbound", rs.Tag)
+		}
+		if rs.Outbound != "reject" && !taken[rs.Outbound] {
+			return nil, skipped, fmt.Errorf("rule set %q routes to unknown outbound %q", rs.Tag, rs.Outbound)
+		}
+		ruleSets = append(ruleSets, map[string]interface{}{
+			"type":            "remote",
+			"tag":             rs.Tag,
+			"format":          valueOr(rs.Format, "binary"),
+			"url":             rs.URL,
+			"download_detour": opts.SelectorTag,
+		})
+		rule := map[string]interface{}{"rule_set": rs.Tag}
+		if rs.Outbound == "reject" {
+			rule["action"] = "reject"
+		} else {
+			rule["outbound"] = rs.Outbound
+		}
+		rules = append(rules, rule)
+	}
+	config.Route = map[string]interface{}{
+		"rules":                   rules,
+		"final":                   opts.SelectorTag,
+		"auto_detect_interface":   true,
+		"default_domain_resolver": "local",
+	}
+	if len(ruleSets) > 0 {
+		config.Route["rule_set"] = ruleSets
+	}
+	return config, skipped, nil
+}
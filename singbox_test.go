@@ -0,0 +1,128 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSingBoxOutbounds(t *testing.T) {
+	outbounds, skipped := SingBoxOutbounds(testExportOutbounds(t), nil)
+	if len(outbounds) != 4 || len(skipped) != 2 {
+		t.Fatalf("Expected 4 outbounds and 2 skipped, got %d and %d", len(outbounds), len(skipped))
+	}
+
+	reality := outbounds[0]
+	if reality.Type != "vless" || reality.Flow != VisionFlow || reality.TLS == nil ||
+		reality.TLS.Reality == nil || reality.TLS.Reality.ShortID != "82c54a0dbca8" ||
+		reality.TLS.UTLS == nil || reality.TLS.UTLS.Fingerprint != "chrome" {
+		t.Errorf("Unexpected reality outbound %+v", reality)
+	}
+	vmess := outbounds[1]
+	if vmess.Security != "auto" || vmess.Transport == nil || vmess.Transport.Type != "ws" ||
+		vmess.Transport.Headers["Host"] != "cdn.example.com" {
+		t.Errorf("Unexpected vmess outbound %+v", vmess)
+	}
+	trojan := outbounds[2]
+	if trojan.Transport == nil || trojan.Transport.ServiceName != "svc" || trojan.TLS.ALPN[0] != "h2" {
+		t.Errorf("Unexpected trojan outbound %+v", trojan)
+	}
+	ss := outbounds[3]
+	if ss.Method != "2022-blake3-aes-128-gcm" || ss.TLS != nil || ss.Transport != nil {
+		t.Errorf("Unexpected shadowsocks outbound %+v", ss)
+	}
+	if !strings.Contains(skipped[0].Err.Error(), `transport "kcp"`) {
+		t.Errorf("Unexpected error for kcp: %v", skipped[0].Err)
+	}
+}
+
+func TestSingBoxOutboundErrors(t *testing.T) {
+	vision, _ := ParseShareLink("vless://uuid@example.com:443?type=tcp&security=tls&flow=xtls-rprx-vision#v")
+	_, skipped := SingBoxOutbounds([]XrayOutbound{*vision}, &SingBoxMultiplex{Enabled: true})
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Err.Error(), "multiplex") {
+		t.Errorf("Expected multiplex with vision to be flagged, got %v", skipped)
+	}
+
+	mux, _ := ParseShareLink("trojan://secret@example.com:443")
+	mux.Mux = map[string]interface{}{"enabled": true, "concurrency": 8}
+	if _, err := NewSingBoxOutbound(*mux); err == nil {
+		t.Error("Expected Xray mux to be flagged")
+	}
+
+	fp, _ := ParseShareLink("trojan://secret@example.com:443?fp=randomizednoalpn")
+	if _, err := NewSingBoxOutbound(*fp); err == nil {
+		t.Error("Expected unknown fingerprint to be flagged")
+	}
+	grpc, _ := ParseShareLink("trojan://secret@example.com:443?type=grpc&serviceName=svc&authority=grpc.example.com&security=tls")
+	if _, err := NewSingBoxOutbound(*grpc); err == nil || !strings.Contains(err.Error(), "authority") {
+		t.Errorf("Expected the gRPC authority to be flagged, got %v", err)
+	}
+}
+
+func TestSingBoxWsEarlyData(t *testing.T) {
+	outbound, _ := ParseShareLink("vless://uuid@example.com:443?type=ws&path=%2Fws%3Fed%3D2048&security=tls")
+	out, err := NewSingBoxOutbound(*outbound)
+	if err != nil {
+		t.Fatalf("NewSingBoxOutbound() error = %v", err)
+	}
+	if out.Transport.Path != "/ws" || out.Transport.MaxEarlyData != 2048 ||
+		out.Transport.EarlyDataHeaderName != "Sec-WebSocket-Protocol" {
+		t.Errorf("Unexpected transport %+v", out.Transport)
+	}
+}
+
+func TestSingBoxProfile(t *testing.T) {
+	config, skipped, err := SingBoxProfile(testExportOutbounds(t), SingBoxOptions{
+		RuleSets: []SingBoxRuleSet{{Tag: "ads", URL: "https://example.com/ads.srs", Outbound: "reject"}},
+	})
+	if err != nil {
+		t.Fatalf("SingBoxProfile() error = %v", err)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("Expected 2 skipped outbounds, got %v", skipped)
+	}
+	// selector, urltest, 4 servers and direct
+	if len(config.Outbounds) != 7 {
+		t.Fatalf("Expected 7 outbounds, got %d", len(config.Outbounds))
+	}
+	selector, urltest := config.Outbounds[0], config.Outbounds[1]
+	if selector.Type != "selector" || selector.Default != "auto" || len(selector.Outbounds) != 5 {
+		t.Errorf("Unexpected selector %+v", selector)
+	}
+	if urltest.Type != "urltest" || urltest.Interval != "5m0s" || len(urltest.Outbounds) != 4 {
+		t.Errorf("Unexpected urltest %+v", urltest)
+	}
+	if config.Route["final"] != "proxy" || config.Inbounds[0]["type"] != "tun" {
+		t.Errorf("Unexpected route %v or inbounds %v", config.Route, config.Inbounds)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"action":"reject","rule_set":"ads"}`) {
+		t.Errorf("Expected reject rule for the rule set, got %s", data)
+	}
+
+	_, _, err = SingBoxProfile(testExportOutbounds(t), SingBoxOptions{
+		RuleSets: []SingBoxRuleSet{{Tag: "cn", URL: "https://example.com/cn.srs", Outbound: "missing"}},
+	})
+	if err == nil {
+		t.Error("Expected error for a rule set routed to an unknown outbound")
+	}
+}
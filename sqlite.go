@@ -0,0 +1,632 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This is a minimal read-only reader of the SQLite file format, enough to
+// walk rowid tables: https://www.sqlite.org/fileformat.html
+
+var errSQLiteCorrupt = errors.New("sqlite: database is corrupt")
+
+type sqliteFile struct {
+	r        io.ReaderAt
+	size     int64
+	pageSize int
+	// Page size minus the reserved bytes at the end of each page.
+	usable int
+}
+
+type sqliteTable struct {
+	name    string
+	root    uint32
+	columns []string
+	// Literal DEFAULT values of the columns, nil for other defaults.
+	defaults []interface{}
+	// Index of the INTEGER PRIMARY KEY column, whose value is the rowid,
+	// or -1.
+	rowidColumn int
+}
+
+// sqliteRow maps column names to int64, float64, string, []byte or nil.
+type sqliteRow map[string]interface{}
+
+func openSQLite(r io.ReaderAt, size int64) (*sqliteFile, error) {
+	header := make([]byte, 100)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNotSQLite
+		}
+		return nil, err
+	}
+	if string(header[:len(sqliteHeader)]) != sqliteHeader {
+		return nil, ErrNotSQLite
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errSQLiteCorrupt
+	}
+	if enc := binary.BigEndian.Uint32(header[56:60]); enc > 1 {
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d", enc)
+	}
+	return &sqliteFile{
+		r:        r,
+		size:     size,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+	}, nil
+}
+
+func (f *sqliteFile) page(n uint32) ([]byte, error) {
+	off := int64(n-1) * int64(f.pageSize)
+	if n == 0 || off+int64(f.pageSize) > f.size {
+		return nil, errSQLiteCorrupt
+	}
+	page := make([]byte, f.pageSize)
+	if read, err := f.r.ReadAt(page, off); read < len(page) {
+		return nil, err
+	}
+	return page, nil
+}
+
+// table looks up a table in the schema.
+func (f *sqliteFile) table(name string) (*sqliteTable, error) {
+	var table *sqliteTable
+	err := f.walkTable(1, func(_ int64, payload []byte) error {
+		values, err := sqliteRecord(payload)
+		if err != nil || len(values) < 5 {
+			return errSQLiteCorrupt
+		}
+		typ, _ := values[0].(string)
+		tblName, _ := values[1].(string)
+		if typ != "table" || !strings.EqualFold(tblName, name) {
+			return nil
+		}
+		root, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+		columns, defaults, rowidColumn, err := sqliteColumns(sql)
+		if err != nil {
+			return fmt.Errorf("sqlite: table %s: %w", name, err)
+		}
+		table = &sqliteTable{name: tblName, root: uint32(root), columns: columns, defaults: defaults, rowidColumn: rowidColumn}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		return nil, fmt.Errorf("sqlite: no such table: %s", name)
+	}
+	return table, nil
+}
+
+// rows calls fn with each row of t in rowid order. Columns added after a
+// row was written get their DEFAULT as SQLite does, except that defaults
+// other than literals, such as expressions, are read as nil.
+func (f *sqliteFile) rows(t *sqliteTable, fn func(sqliteRow) error) error {
+	return f.walkTable(t.root, func(rowid int64, payload []byte) error {
+		values, err := sqliteRecord(payload)
+		if err != nil {
+			return err
+		}
+		row := make(sqliteRow, len(t.columns))
+		for i, column := range t.columns {
+			if i < len(values) {
+				row[column] = values[i]
+			} else {
+				row[column] = t.defaults[i]
+			}
+		}
+		if t.rowidColumn >= 0 {
+			row[t.columns[t.rowidColumn]] = rowid
+		}
+		return fn(row)
+	})
+}
+
+// walkTable calls fn with the rowid and payload of each cell of the table
+// b-tree rooted at page root.
+func (f *sqliteFile) walkTable(root uint32, fn func(int64, []byte) error) error {
+	return f.walkPage(root, fn, 0)
+}
+
+func (f *sqliteFile) walkPage(n uint32, fn func(int64, []byte) error, depth int) error {
+	if depth > 32 {
+		return errSQLiteCorrupt
+	}
+	page, err := f.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	cells := int(binary.BigEndian.Uint16(page[hdr+3:]))
+
+	switch page[hdr] {
+	case 0x05: // interior table page
+		if hdr+12+2*cells > len(page) {
+			return errSQLiteCorrupt
+		}
+		for i := 0; i < cells; i++ {
+			off := int(binary.BigEndian.Uint16(page[hdr+12+2*i:]))
+			if off+4 > len(page) {
+				return errSQLiteCorrupt
+			}
+			if err := f.walkPage(binary.BigEndian.Uint32(page[off:]), fn, depth+1); err != nil {
+				return err
+			}
+		}
+		return f.walkPage(binary.BigEndian.Uint32(page[hdr+8:]), fn, depth+1)
+
+	case 0x0d: // leaf table page
+		if hdr+8+2*cells > len(page) {
+			return errSQLiteCorrupt
+		}
+		for i := 0; i < cells; i++ {
+			off := int(binary.BigEndian.Uint16(page[hdr+8+2*i:]))
+			if off >= len(page) {
+				return errSQLiteCorrupt
+			}
+			size, k := sqliteVarint(page[off:])
+			if k == 0 {
+				return errSQLiteCorrupt
+			}
+			off += k
+			rowid, k := sqliteVarint(page[off:])
+			if k == 0 {
+				return errSQLiteCorrupt
+			}
+			payload, err := f.payload(page, off+k, size)
+			if err != nil {
+				return err
+			}
+			if err := fn(int64(rowid), payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("sqlite: page %d is not a table page", n)
+}
+
+// payload returns the payload of size bytes starting at off on page,
+// following overflow pages.
+func (f *sqliteFile) payload(page []byte, off int, size uint64) ([]byte, error) {
+	if size > uint64(f.size) {
+		return nil, errSQLiteCorrupt
+	}
+	u := f.usable
+	maxLocal := u - 35
+	local := int(size)
+	if local > maxLocal {
+		minLocal := (u-12)*32/255 - 23
+		local = minLocal + (int(size)-minLocal)%(u-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if off+local > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+	if local == int(size) {
+		return page[off : off+local], nil
+	}
+	if off+local+4 > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, page[off:off+local]...)
+	next := binary.BigEndian.Uint32(page[off+local:])
+	for len(out) < int(size) {
+		if next == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		overflow, err := f.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(overflow)
+		n := min(int(size)-len(out), u-4)
+		out = append(out, overflow[4:4+n]...)
+	}
+	return out, nil
+}
+
+// sqliteVarint decodes a big-endian varint of up to 9 bytes. It returns
+// the number of bytes read, 0 if b is too short.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// sqliteRecord decodes a record into int64, float64, string, []byte or
+// nil values.
+func sqliteRecord(b []byte) ([]interface{}, error) {
+	hdrLen, n := sqliteVarint(b)
+	if n == 0 || hdrLen > uint64(len(b)) {
+		return nil, errSQLiteCorrupt
+	}
+	var types []uint64
+	for pos := n; pos < int(hdrLen); {
+		t, k := sqliteVarint(b[pos:hdrLen])
+		if k == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		types = append(types, t)
+		pos += k
+	}
+
+	body := b[hdrLen:]
+	values := make([]interface{}, len(types))
+	for i, t := range types {
+		var size int
+		switch {
+		case t == 0, t == 8, t == 9:
+		case t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			size = int((t - 12) / 2)
+		default:
+			return nil, errSQLiteCorrupt
+		}
+		if size > len(body) {
+			return nil, errSQLiteCorrupt
+		}
+		v := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t == 8:
+			values[i] = int64(0)
+		case t == 9:
+			values[i] = int64(1)
+		case t <= 6:
+			var n int64
+			if v[0]&0x80 != 0 {
+				n = -1
+			}
+			for _, c := range v {
+				n = n<<8 | int64(c)
+			}
+			values[i] = n
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(v))
+		case t%2 == 0:
+			values[i] = append([]byte(nil), v...)
+		default:
+			values[i] = string(v)
+		}
+	}
+	return values, nil
+}
+
+// sqliteColumns returns the column names of a CREATE TABLE statement, their
+// literal defaults and the index of its INTEGER PRIMARY KEY column, or -1.
+func sqliteColumns(sql string) ([]string, []interface{}, int, error) {
+	start, end := strings.IndexByte(sql, '('), strings.LastIndexByte(sql, ')')
+	if start < 0 || end < start {
+		return nil, nil, -1, fmt.Errorf("unsupported schema %q", sql)
+	}
+	if strings.Contains(strings.ToUpper(sql[end:]), "WITHOUT ROWID") {
+		return nil, nil, -1, errors.New("WITHOUT ROWID tables are not supported")
+	}
+
+	var columns, types []string
+	var defaults []interface{}
+	rowidColumn := -1
+	var tablePK []string
+	for _, def := range splitSQLDefs(sql[start+1 : end]) {
+		name, rest, quoted := sqlIdentifier(def)
+		upper := strings.ToUpper(rest)
+		if !quoted {
+			switch strings.ToUpper(name) {
+			case "PRIMARY":
+				if open, end := strings.IndexByte(def, '('), strings.LastIndexByte(def, ')'); open >= 0 && end > open {
+					tablePK = splitSQLDefs(def[open+1 : end])
+				}
+				continue
+			case "CONSTRAINT", "UNIQUE", "CHECK", "FOREIGN":
+				continue
+			}
+		}
+		columns = append(columns, name)
+		defaults = append(defaults, sqlDefault(rest))
+		typ := strings.Fields(upper)
+		if len(typ) > 0 {
+			types = append(types, typ[0])
+		} else {
+			types = append(types, "")
+		}
+		if len(typ) > 0 && typ[0] == "INTEGER" && strings.Contains(upper, "PRIMARY KEY") {
+			rowidColumn = len(columns) - 1
+		}
+	}
+	if len(tablePK) == 1 {
+		pk, _, _ := sqlIdentifier(tablePK[0])
+		for i, column := range columns {
+			if strings.EqualFold(column, pk) && types[i] == "INTEGER" {
+				rowidColumn = i
+			}
+		}
+	}
+	return columns, defaults, rowidColumn, nil
+}
+
+// sqlDefault returns the value of the DEFAULT clause in a column definition
+// if it is a literal: a number, string, blob, NULL, TRUE or FALSE. Other
+// defaults are nil.
+func sqlDefault(def string) interface{} {
+	i := sqlKeyword(def, "DEFAULT")
+	if i < 0 {
+		return nil
+	}
+	value := strings.TrimSpace(def[i+len("DEFAULT"):])
+	// A parenthesized default must be a single literal, not an expression.
+	enclosed := false
+	for strings.HasPrefix(value, "(") {
+		end := sqlClosingParen(value)
+		if end < 0 {
+			return nil
+		}
+		value, enclosed = strings.TrimSpace(value[1:end]), true
+	}
+
+	if value != "" && (value[0] == '\'' || (len(value) > 1 && (value[0] == 'x' || value[0] == 'X') && value[1] == '\'')) {
+		blob := value[0] != '\''
+		if blob {
+			value = value[1:]
+		}
+		var b strings.Builder
+		j := 1
+		for ; j < len(value); j++ {
+			if value[j] != '\'' {
+				b.WriteByte(value[j])
+			} else if j+1 < len(value) && value[j+1] == '\'' {
+				b.WriteByte('\'')
+				j++
+			} else {
+				break
+			}
+		}
+		if j == len(value) || enclosed && strings.TrimSpace(value[j+1:]) != "" {
+			return nil
+		}
+		if blob {
+			data, err := hex.DecodeString(b.String())
+			if err != nil {
+				return nil
+			}
+			return data
+		}
+		return b.String()
+	}
+
+	if end := strings.IndexFunc(value, unicode.IsSpace); end >= 0 {
+		if enclosed {
+			return nil
+		}
+		value = value[:end]
+	}
+	switch strings.ToUpper(value) {
+	case "NULL":
+		return nil
+	case "TRUE":
+		return int64(1)
+	case "FALSE":
+		return int64(0)
+	}
+	sign, digits := "", value
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if digits == "" || !(digits[0] >= '0' && digits[0] <= '9' || digits[0] == '.') {
+		return nil
+	}
+	if len(digits) > 2 && (digits[:2] == "0x" || digits[:2] == "0X") {
+		// Hex literals are 64-bit two's complement.
+		u, err := strconv.ParseUint(digits[2:], 16, 64)
+		if err != nil {
+			return nil
+		}
+		if sign == "-" {
+			return -int64(u)
+		}
+		return int64(u)
+	}
+	if n, err := strconv.ParseInt(sign+digits, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(sign+digits, 64); err == nil {
+		return f
+	}
+	return nil
+}
+
+// sqlKeyword returns the index of keyword in def outside of quotes and
+// parentheses, or -1.
+func sqlKeyword(def, keyword string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(def); i++ {
+		c := def[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && len(def)-i >= len(keyword) && strings.EqualFold(def[i:i+len(keyword)], keyword) &&
+			(i == 0 || !isSQLIdentChar(def[i-1])) && (i+len(keyword) == len(def) || !isSQLIdentChar(def[i+len(keyword)])):
+			return i
+		}
+	}
+	return -1
+}
+
+// sqlClosingParen returns the index of the parenthesis closing the one s
+// starts with, or -1.
+func sqlClosingParen(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// splitSQLDefs splits s at commas outside of parentheses and quotes.
+func splitSQLDefs(s string) []string {
+	var defs []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(defs, strings.TrimSpace(s[start:]))
+}
+
+// sqlIdentifier splits the leading identifier off def.
+func sqlIdentifier(def string) (name, rest string, quoted bool) {
+	def = strings.TrimSpace(def)
+	if def == "" {
+		return "", "", false
+	}
+	closing := map[byte]byte{'`': '`', '"': '"', '[': ']'}[def[0]]
+	if closing != 0 {
+		if end := strings.IndexByte(def[1:], closing); end >= 0 {
+			return def[1 : end+1], strings.TrimSpace(def[end+2:]), true
+		}
+	}
+	if end := strings.IndexFunc(def, unicode.IsSpace); end >= 0 {
+		return def[:end], strings.TrimSpace(def[end:]), false
+	}
+	return def, "", false
+}
+
+func (r sqliteRow) integer(column string) int64 {
+	switch v := r[column].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n
+	}
+	return 0
+}
+
+func (r sqliteRow) text(column string) string {
+	switch v := r[column].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func (r sqliteRow) boolean(column string) bool {
+	switch v := r[column].(type) {
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	case nil:
+		return false
+	}
+	return r.integer(column) != 0
+}
@@ -0,0 +1,142 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// XrayTransition is a change of the Xray process state between two polls.
+type XrayTransition struct {
+	From ProcessState
+	To   ProcessState
+}
+
+// StatusSnapshot is one poll of the server status, with values derived from
+// the previous successful poll.
+type StatusSnapshot struct {
+	Time time.Time
+	// Nil if the poll failed.
+	Status *ServerStatus
+	Err    error
+
+	// Time since the previous successful poll, zero for the first one.
+	Elapsed time.Duration
+	// Traffic rates in bytes per second from NetTraffic. Zero for the first
+	// poll and after a counter reset.
+	UpRate   ByteSize
+	DownRate ByteSize
+	// Change of CPU usage in percentage points.
+	CpuDelta float64
+	TcpDelta int
+	UdpDelta int
+	// The traffic counters or the uptime went backwards, usually because
+	// the server rebooted.
+	CounterReset bool
+	// Set when the Xray state differs from the previous poll.
+	XrayTransition *XrayTransition
+}
+
+// nextSnapshot derives a snapshot of status polled at now from the
+// previous successful snapshot, which is nil for the first poll.
+func nextSnapshot(prev *StatusSnapshot, status *ServerStatus, now time.Time) StatusSnapshot {
+	snap := StatusSnapshot{Time: now, Status: status}
+	if prev == nil || prev.Status == nil {
+		return snap
+	}
+	old := prev.Status
+	snap.Elapsed = now.Sub(prev.Time)
+	snap.CpuDelta = status.Cpu - old.Cpu
+	snap.TcpDelta = status.TcpCount - old.TcpCount
+	snap.UdpDelta = status.UdpCount - old.UdpCount
+	if status.Xray.State != old.Xray.State {
+		snap.XrayTransition = &XrayTransition{From: old.Xray.State, To: status.Xray.State}
+	}
+
+	sent, recv := status.NetTraffic.Sent, status.NetTraffic.Recv
+	if sent < old.NetTraffic.Sent || recv < old.NetTraffic.Recv || status.Uptime < old.Uptime {
+		snap.CounterReset = true
+		return snap
+	}
+	if seconds := snap.Elapsed.Seconds(); seconds > 0 {
+		snap.UpRate = ByteSize(float64(sent-old.NetTraffic.Sent) / seconds)
+		snap.DownRate = ByteSize(float64(recv-old.NetTraffic.Recv) / seconds)
+	}
+	return snap
+}
+
+// WatchStatusFunc polls ServerStatus every interval, starting immediately,
+// and calls fn with each snapshot. Failed polls are delivered with Err set
+// and don't reset the rates. It returns ctx.Err() once ctx is done, or an
+// error right away if interval isn't positive.
+func (c *Client) WatchStatusFunc(ctx context.Context, interval time.Duration, fn func(StatusSnapshot)) error {
+	if err := checkPollInterval(interval); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev *StatusSnapshot
+	for {
+		status, err := c.ServerStatus(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fn(StatusSnapshot{Time: time.Now(), Err: err})
+		} else {
+			snap := nextSnapshot(prev, status, time.Now())
+			prev = &snap
+			fn(snap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WatchStatus is WatchStatusFunc delivering snapshots over a channel, which
+// is closed once ctx is done. Polling waits while the receiver is busy.
+func (c *Client) WatchStatus(ctx context.Context, interval time.Duration) (<-chan StatusSnapshot, error) {
+	if err := checkPollInterval(interval); err != nil {
+		return nil, err
+	}
+	ch := make(chan StatusSnapshot)
+	go func() {
+		defer close(ch)
+		_ = c.WatchStatusFunc(ctx, interval, func(snap StatusSnapshot) {
+			select {
+			case ch <- snap:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	return ch, nil
+}
+
+// checkPollInterval rejects intervals time.NewTicker would panic on.
+func checkPollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %v", interval)
+	}
+	return nil
+}
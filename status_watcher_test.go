@@ -0,0 +1,158 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNextSnapshot(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	status := func(cpu float64, sent, recv ByteSize, uptime uint64, tcp int, state ProcessState) *ServerStatus {
+		return &ServerStatus{
+			Cpu:        cpu,
+			Uptime:     uptime,
+			TcpCount:   tcp,
+			NetTraffic: NetTraffic{Sent: sent, Recv: recv},
+			Xray:       XrayStatus{State: state},
+		}
+	}
+
+	first := nextSnapshot(nil, status(10, 1*MiB, 2*MiB, 100, 5, Running), start)
+	if first.Elapsed != 0 || first.UpRate != 0 || first.XrayTransition != nil {
+		t.Errorf("Expected no derived values for the first snapshot, got %+v", first)
+	}
+
+	second := nextSnapshot(&first, status(25, 11*MiB, 42*MiB, 110, 8, Running), start.Add(10*time.Second))
+	if second.UpRate != MiB || second.DownRate != 4*MiB {
+		t.Errorf("Expected 1MiB/s up and 4MiB/s down, got %s and %s", second.UpRate, second.DownRate)
+	}
+	if second.CpuDelta != 15 || second.TcpDelta != 3 || second.CounterReset {
+		t.Errorf("Unexpected deltas %+v", second)
+	}
+
+	rebooted := nextSnapshot(&second, status(5, 1*KiB, 1*KiB, 20, 1, Stop), start.Add(20*time.Second))
+	if !rebooted.CounterReset || rebooted.UpRate != 0 || rebooted.DownRate != 0 {
+		t.Errorf("Expected a counter reset without rates, got %+v", rebooted)
+	}
+	if rebooted.XrayTransition == nil || rebooted.XrayTransition.From != Running || rebooted.XrayTransition.To != Stop {
+		t.Errorf("Expected running -> stop transition, got %+v", rebooted.XrayTransition)
+	}
+}
+
+func TestWatchStatusInterval(t *testing.T) {
+	c := newFakePanel(t).client()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := c.WatchStatusFunc(context.Background(), interval, func(StatusSnapshot) {}); err == nil {
+			t.Errorf("Expected an error for interval %v", interval)
+		}
+		if ch, err := c.WatchStatus(context.Background(), interval); err == nil || ch != nil {
+			t.Errorf("Expected an error for interval %v", interval)
+		}
+	}
+}
+
+// handleStatusPolls answers /server/status with traffic growing by 1MiB
+// sent per poll, failing the second poll.
+func handleStatusPolls(p *fakePanel) {
+	var mu sync.Mutex
+	polls := 0
+	p.handle("/server/status", func(*http.Request) (int, []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		if polls == 2 {
+			return apiFailure("status unavailable")
+		}
+		return apiSuccess(ServerStatus{
+			Uptime:     uint64(polls),
+			NetTraffic: NetTraffic{Sent: ByteSize(polls) * MiB},
+			Xray:       XrayStatus{State: Running},
+		})
+	})
+}
+
+func checkSnapshots(t *testing.T, snaps []StatusSnapshot) {
+	t.Helper()
+	if snaps[0].Err != nil || snaps[0].Status == nil || snaps[0].UpRate != 0 {
+		t.Errorf("Unexpected first snapshot %+v", snaps[0])
+	}
+	if snaps[1].Err == nil || snaps[1].Err.Error() != "status unavailable" || snaps[1].Status != nil {
+		t.Errorf("Expected the failed poll with Err set, got %+v", snaps[1])
+	}
+	// Rates are derived from the poll before the failed one.
+	if snaps[2].Err != nil || snaps[2].UpRate <= 0 || snaps[2].Elapsed <= 0 || snaps[2].CounterReset {
+		t.Errorf("Expected rates after the failed poll, got %+v", snaps[2])
+	}
+}
+
+func TestWatchStatusFunc(t *testing.T) {
+	p := newFakePanel(t)
+	handleStatusPolls(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var snaps []StatusSnapshot
+	err := p.client().WatchStatusFunc(ctx, time.Millisecond, func(snap StatusSnapshot) {
+		snaps = append(snaps, snap)
+		if len(snaps) == 3 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("Expected 3 snapshots, got %d", len(snaps))
+	}
+	checkSnapshots(t, snaps)
+}
+
+func TestWatchStatus(t *testing.T) {
+	p := newFakePanel(t)
+	handleStatusPolls(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := p.client().WatchStatus(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snaps []StatusSnapshot
+	for len(snaps) < 3 {
+		snaps = append(snaps, <-ch)
+	}
+	checkSnapshots(t, snaps)
+
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected the channel to be closed")
+		}
+	}
+}
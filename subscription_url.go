@@ -0,0 +1,144 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Default subscription paths of the panel.
+const (
+	DefaultSubPath     = "/sub/"
+	DefaultSubJsonPath = "/json/"
+)
+
+// ErrSubDisabled is returned when the subscription server is turned off in
+// the panel settings.
+var ErrSubDisabled = errors.New("subscription server is disabled")
+
+// SubscriptionURL returns the base64 subscription URL the panel serves for
+// subID. host is the panel's host name (a port is ignored) and is used when
+// SubDomain is empty, like the panel does with the host of the request.
+func (s PanelSettings) SubscriptionURL(subID, host string) (string, error) {
+	base, err := s.subscriptionBase(s.SubURI, s.SubPath, DefaultSubPath, host)
+	if err != nil {
+		return "", err
+	}
+	return base + url.PathEscape(subID), nil
+}
+
+// SubscriptionJsonURL is SubscriptionURL for the JSON subscription.
+func (s PanelSettings) SubscriptionJsonURL(subID, host string) (string, error) {
+	base, err := s.subscriptionBase(s.SubJsonURI, s.SubJsonPath, DefaultSubJsonPath, host)
+	if err != nil {
+		return "", err
+	}
+	return base + url.PathEscape(subID), nil
+}
+
+// subscriptionBase returns the URL the subscription ID is appended to. An
+// explicit uri wins, otherwise it's built from the sub server settings.
+func (s PanelSettings) subscriptionBase(uri, path, defaultPath, host string) (string, error) {
+	if !s.SubEnable {
+		return "", ErrSubDisabled
+	}
+	if uri != "" {
+		if !strings.HasSuffix(uri, "/") {
+			uri += "/"
+		}
+		return uri, nil
+	}
+
+	domain := s.SubDomain
+	if domain == "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		domain = strings.Trim(host, "[]")
+	}
+	if domain == "" {
+		return "", errors.New("no subscription domain or host")
+	}
+
+	// The sub server only serves TLS when both files are set.
+	tls := s.SubCertFile != "" && s.SubKeyFile != ""
+	scheme := "http://"
+	if tls {
+		scheme = "https://"
+	}
+	var hostPort string
+	if (tls && s.SubPort == 443) || (!tls && s.SubPort == 80) || s.SubPort == 0 {
+		hostPort = domain
+		if strings.Contains(domain, ":") {
+			hostPort = "[" + domain + "]"
+		}
+	} else {
+		hostPort = net.JoinHostPort(domain, strconv.Itoa(s.SubPort))
+	}
+
+	path = strings.Trim(path, "/")
+	if path == "" {
+		path = strings.Trim(defaultPath, "/")
+	}
+	return scheme + hostPort + "/" + path + "/", nil
+}
+
+// subscriptionBases returns the base64 and JSON subscription base URLs.
+// Config.SubUrl is used with the default paths when set, otherwise they are
+// discovered from the panel settings once and cached, along with the
+// subscription encoding.
+func (c *Client) subscriptionBases(ctx context.Context) (sub, json string, err error) {
+	if c.subUrl != "" {
+		base := strings.TrimSuffix(c.subUrl, "/")
+		return base + DefaultSubPath, base + DefaultSubJsonPath, nil
+	}
+	if c.subURI != "" {
+		return c.subURI, c.subJsonURI, nil
+	}
+	settings, err := c.GetPanelSettings(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", "", err
+	}
+	s := settings.Obj
+	if sub, err = s.subscriptionBase(s.SubURI, s.SubPath, DefaultSubPath, u.Host); err != nil {
+		return "", "", err
+	}
+	if json, err = s.subscriptionBase(s.SubJsonURI, s.SubJsonPath, DefaultSubJsonPath, u.Host); err != nil {
+		return "", "", err
+	}
+	c.subURI, c.subJsonURI = sub, json
+	c.subEncoding = s.SubscriptionEncoding()
+	return sub, json, nil
+}
+
+// SubscriptionURLs returns the base64 and JSON subscription URLs of subID.
+func (c *Client) SubscriptionURLs(ctx context.Context, subID string) (sub, json string, err error) {
+	sub, json, err = c.subscriptionBases(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return sub + url.PathEscape(subID), json + url.PathEscape(subID), nil
+}
@@ -0,0 +1,86 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscriptionURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings PanelSettings
+		host     string
+		sub      string
+		json     string
+	}{
+		{
+			name:     "Defaults",
+			settings: PanelSettings{SubEnable: true, SubPort: 2096, SubPath: "/sub/", SubJsonPath: "/json/"},
+			host:     "panel.example.com:2053",
+			sub:      "http://panel.example.com:2096/sub/abc",
+			json:     "http://panel.example.com:2096/json/abc",
+		},
+		{
+			name: "TLS on default port",
+			settings: PanelSettings{SubEnable: true, SubPort: 443, SubPath: "s", SubJsonPath: "j/",
+				SubDomain: "sub.example.com", SubCertFile: "/cert.pem", SubKeyFile: "/key.pem"},
+			host: "panel.example.com",
+			sub:  "https://sub.example.com/s/abc",
+			json: "https://sub.example.com/j/abc",
+		},
+		{
+			name:     "Cert without key is plain HTTP",
+			settings: PanelSettings{SubEnable: true, SubPort: 443, SubPath: "/sub", SubCertFile: "/cert.pem"},
+			host:     "1.2.3.4",
+			sub:      "http://1.2.3.4:443/sub/abc",
+			json:     "http://1.2.3.4:443/json/abc",
+		},
+		{
+			name: "Explicit URIs",
+			settings: PanelSettings{SubEnable: true, SubPort: 2096, SubPath: "/sub/",
+				SubURI: "https://cdn.example.com/s", SubJsonURI: "https://cdn.example.com/j/"},
+			host: "panel.example.com",
+			sub:  "https://cdn.example.com/s/abc",
+			json: "https://cdn.example.com/j/abc",
+		},
+		{
+			name:     "IPv6 host",
+			settings: PanelSettings{SubEnable: true, SubPort: 2096, SubPath: "/sub/"},
+			host:     "[2001:db8::1]:2053",
+			sub:      "http://[2001:db8::1]:2096/sub/abc",
+			json:     "http://[2001:db8::1]:2096/json/abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := tt.settings.SubscriptionURL("abc", tt.host)
+			if err != nil || sub != tt.sub {
+				t.Errorf("SubscriptionURL() = %q, %v, want %q", sub, err, tt.sub)
+			}
+			json, err := tt.settings.SubscriptionJsonURL("abc", tt.host)
+			if err != nil || json != tt.json {
+				t.Errorf("SubscriptionJsonURL() = %q, %v, want %q", json, err, tt.json)
+			}
+		})
+	}
+
+	if _, err := (PanelSettings{}).SubscriptionURL("abc", "example.com"); !errors.Is(err, ErrSubDisabled) {
+		t.Errorf("Expected ErrSubDisabled, got %v", err)
+	}
+}
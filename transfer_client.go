@@ -0,0 +1,241 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// VisionFlow is the XTLS flow used by VLESS clients on TCP with TLS or Reality.
+const VisionFlow = "xtls-rprx-vision"
+
+// ConvertClient translates a client for use in the target inbound. UUID,
+// subscription ID, expiry, IP limit and comment are kept. The quota is set
+// to what remains of stat, since the target starts with fresh counters; a
+// client that used up its quota is disabled with a one byte quota. Flow is
+// set to VisionFlow for VLESS targets on TCP with TLS or Reality and dropped
+// otherwise. VLESS and VMess targets get a fresh UUID if the client has none,
+// as trojan and shadowsocks clients may not. Trojan and shadowsocks targets reuse the password, or the UUID
+// when there is none, except that Shadowsocks-2022 needs a key of the
+// method's length and gets a fresh one if the old password doesn't fit.
+func ConvertClient(client InboundClient, stat ClientStat, target Inbound) (XrayClient, error) {
+	out := XrayClient{
+		ID:         client.ID,
+		Password:   client.Password,
+		Email:      client.Email,
+		LimitIP:    uint(max(client.LimitIp, 0)),
+		TotalGB:    client.TotalGB,
+		ExpiryTime: client.ExpiryTime,
+		Enable:     client.Enable,
+		SubID:      client.SubId,
+		Comment:    client.Comment,
+	}
+	if stat.Total > 0 {
+		out.TotalGB = stat.Remaining()
+		if out.TotalGB == 0 {
+			out.TotalGB = 1
+			out.Enable = false
+		}
+	}
+
+	var stream struct {
+		Network  string `json:"network"`
+		Security string `json:"security"`
+	}
+	if target.StreamSettings != "" {
+		if err := json.Unmarshal([]byte(target.StreamSettings), &stream); err != nil {
+			return XrayClient{}, fmt.Errorf("invalid stream settings of inbound %d: %w", target.ID, err)
+		}
+	}
+
+	switch target.Protocol {
+	case "vless":
+		if err := ensureUUID(&out); err != nil {
+			return XrayClient{}, err
+		}
+		out.Password = ""
+		if stream.Network == "tcp" && (stream.Security == "tls" || stream.Security == "reality") {
+			out.Flow = VisionFlow
+		}
+	case "vmess":
+		if err := ensureUUID(&out); err != nil {
+			return XrayClient{}, err
+		}
+		out.Password = ""
+		out.Security = client.Security
+		if out.Security == "" {
+			out.Security = "auto"
+		}
+	case "trojan":
+		if out.Password == "" {
+			out.Password = client.ID
+		}
+	case "shadowsocks":
+		var settings struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal([]byte(target.Settings), &settings); err != nil {
+			return XrayClient{}, fmt.Errorf("invalid settings of inbound %d: %w", target.ID, err)
+		}
+		if out.Password == "" {
+			out.Password = client.ID
+		}
+		if size := ss2022KeySize(settings.Method); size > 0 {
+			if key, err := base64.StdEncoding.DecodeString(out.Password); err != nil || len(key) != size {
+				if out.Password, err = randomBase64Key(size); err != nil {
+					return XrayClient{}, err
+				}
+			}
+		}
+	default:
+		return XrayClient{}, fmt.Errorf("unsupported target protocol %q", target.Protocol)
+	}
+	return out, nil
+}
+
+func ensureUUID(client *XrayClient) error {
+	if client.ID != "" {
+		return nil
+	}
+	id, err := NewUUID()
+	if err != nil {
+		return err
+	}
+	client.ID = id
+	return nil
+}
+
+// ss2022KeySize returns the key length of a Shadowsocks-2022 method, or zero
+// for other methods.
+func ss2022KeySize(method string) int {
+	switch method {
+	case "2022-blake3-aes-128-gcm":
+		return 16
+	case "2022-blake3-aes-256-gcm", "2022-blake3-chacha20-poly1305":
+		return 32
+	}
+	return 0
+}
+
+func randomBase64Key(size int) (string, error) {
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func findInbound(inbounds []Inbound, id int) (*Inbound, error) {
+	for i := range inbounds {
+		if inbounds[i].ID == id {
+			return &inbounds[i], nil
+		}
+	}
+	return nil, fmt.Errorf("inbound %d not found", id)
+}
+
+// clientTransfer is a client ready to be added to another inbound.
+type clientTransfer struct {
+	source, target *Inbound
+	client         *InboundClient
+	converted      XrayClient
+}
+
+// prepareTransfer looks up the client and converts it for the target inbound.
+func (c *Client) prepareTransfer(ctx context.Context, email string, targetInboundID int) (*clientTransfer, error) {
+	inbounds, err := c.GetInbounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, client, err := findClient(inbounds.Obj, email)
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == targetInboundID {
+		return nil, fmt.Errorf("client %s is already in inbound %d", email, targetInboundID)
+	}
+	target, err := findInbound(inbounds.Obj, targetInboundID)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := c.GetClientByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic of %s: %w", email, err)
+	}
+	converted, err := ConvertClient(*client, *stat, *target)
+	if err != nil {
+		return nil, err
+	}
+	return &clientTransfer{source: source, target: target, client: client, converted: converted}, nil
+}
+
+// CopyClient adds a copy of a client to another inbound under newEmail, since
+// the panel requires emails to be unique across inbounds. See ConvertClient
+// for how fields are translated.
+func (c *Client) CopyClient(ctx context.Context, email string, targetInboundID int, newEmail string) (*XrayClient, error) {
+	if newEmail == "" || newEmail == email {
+		return nil, fmt.Errorf("copy of %s needs a different email", email)
+	}
+	t, err := c.prepareTransfer(ctx, email, targetInboundID)
+	if err != nil {
+		return nil, err
+	}
+	t.converted.Email = newEmail
+	if _, err := c.AddClient(ctx, uint(targetInboundID), []XrayClient{t.converted}); err != nil {
+		return nil, err
+	}
+	return &t.converted, nil
+}
+
+// MoveClient moves a client to another inbound, keeping its email. The client
+// is added to the target under a temporary email first, deleted from the
+// source only once that succeeded, and then renamed back.
+func (c *Client) MoveClient(ctx context.Context, email string, targetInboundID int) (*XrayClient, error) {
+	t, err := c.prepareTransfer(ctx, email, targetInboundID)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	tempEmail := email + "-moving-" + hex.EncodeToString(suffix)
+	t.converted.Email = tempEmail
+	if _, err := c.AddClient(ctx, uint(targetInboundID), []XrayClient{t.converted}); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.DeleteClient(ctx, uint(t.source.ID), ClientKey(t.source.Protocol, *t.client)); err != nil {
+		temp := InboundClient{ID: t.converted.ID, Password: t.converted.Password, Email: tempEmail}
+		if _, cleanupErr := c.DeleteClient(ctx, uint(targetInboundID), ClientKey(t.target.Protocol, temp)); cleanupErr != nil {
+			return nil, fmt.Errorf("failed to delete %s from inbound %d: %w (temporary client %s left in inbound %d: %v)",
+				email, t.source.ID, err, tempEmail, targetInboundID, cleanupErr)
+		}
+		return nil, fmt.Errorf("failed to delete %s from inbound %d: %w", email, t.source.ID, err)
+	}
+
+	if err := c.RenameClientEmail(ctx, tempEmail, email); err != nil {
+		return nil, fmt.Errorf("client moved as %s but renaming back failed: %w", tempEmail, err)
+	}
+	t.converted.Email = email
+	return &t.converted, nil
+}
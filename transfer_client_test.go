@@ -0,0 +1,294 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConvertClient(t *testing.T) {
+	client := InboundClient{
+		ID:         "8e72473d-3c52-4153-b5ba-3b06035d0ad1",
+		Email:      "user@example.com",
+		Enable:     true,
+		ExpiryTime: ExpiresAfterFirstUse(30),
+		SubId:      "sub123",
+		TotalGB:    100 * GiB,
+		Flow:       VisionFlow,
+	}
+	stat := ClientStat{Email: client.Email, Up: 10 * GiB, Down: 30 * GiB, Total: 100 * GiB}
+
+	tests := []struct {
+		name   string
+		target Inbound
+		check  func(t *testing.T, got XrayClient)
+	}{
+		{
+			name: "VLESS Reality",
+			target: Inbound{
+				Protocol:       "vless",
+				StreamSettings: `{"network":"tcp","security":"reality"}`,
+			},
+			check: func(t *testing.T, got XrayClient) {
+				if got.Flow != VisionFlow {
+					t.Errorf("Expected flow %q, got %q", VisionFlow, got.Flow)
+				}
+			},
+		},
+		{
+			name: "VMess WebSocket",
+			target: Inbound{
+				Protocol:       "vmess",
+				StreamSettings: `{"network":"ws","security":"none"}`,
+			},
+			check: func(t *testing.T, got XrayClient) {
+				if got.Flow != "" {
+					t.Errorf("Expected no flow, got %q", got.Flow)
+				}
+				if got.Security != "auto" {
+					t.Errorf("Expected security 'auto', got %q", got.Security)
+				}
+			},
+		},
+		{
+			name:   "Trojan",
+			target: Inbound{Protocol: "trojan", StreamSettings: `{"network":"grpc","security":"tls"}`},
+			check: func(t *testing.T, got XrayClient) {
+				if got.Password != client.ID {
+					t.Errorf("Expected UUID as password, got %q", got.Password)
+				}
+			},
+		},
+		{
+			name:   "Shadowsocks 2022",
+			target: Inbound{Protocol: "shadowsocks", Settings: `{"method":"2022-blake3-aes-128-gcm"}`},
+			check: func(t *testing.T, got XrayClient) {
+				key, err := base64.StdEncoding.DecodeString(got.Password)
+				if err != nil || len(key) != 16 {
+					t.Errorf("Expected 16 byte base64 key, got %q", got.Password)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertClient(client, stat, tt.target)
+			if err != nil {
+				t.Fatalf("ConvertClient() error = %v", err)
+			}
+			if got.ID != client.ID || got.SubID != client.SubId || got.ExpiryTime != client.ExpiryTime {
+				t.Errorf("Credentials not kept: %+v", got)
+			}
+			if got.TotalGB != 60*GiB {
+				t.Errorf("Expected remaining quota 60GiB, got %v", got.TotalGB)
+			}
+			tt.check(t, got)
+		})
+	}
+
+	exhausted := ClientStat{Up: 100 * GiB, Total: 100 * GiB}
+	got, err := ConvertClient(client, exhausted, Inbound{Protocol: "vless"})
+	if err != nil {
+		t.Fatalf("ConvertClient() error = %v", err)
+	}
+	if got.TotalGB == 0 || got.Enable {
+		t.Errorf("Exhausted client must stay limited and disabled, got %+v", got)
+	}
+
+	trojan := InboundClient{Password: "trojan-pass", Email: "trojan@example.com", Enable: true}
+	got, err = ConvertClient(trojan, ClientStat{}, Inbound{Protocol: "vless"})
+	if err != nil {
+		t.Fatalf("ConvertClient() error = %v", err)
+	}
+	if len(got.ID) != 36 || got.Password != "" {
+		t.Errorf("Expected a fresh UUID and no password, got %+v", got)
+	}
+
+	if _, err := ConvertClient(client, stat, Inbound{Protocol: "wireguard"}); err == nil {
+		t.Error("Expected error for unsupported protocol")
+	}
+}
+
+const aliceUUID = "8e72473d-3c52-4153-b5ba-3b06035d0ad1"
+
+// transferPanel serves VLESS inbounds 1 with alice and 2 without clients,
+// and keeps track of the clients added, deleted and updated.
+type transferPanel struct {
+	*fakePanel
+	clients map[int][]InboundClient
+}
+
+func newTransferPanel(t *testing.T) *transferPanel {
+	p := &transferPanel{
+		fakePanel: newFakePanel(t),
+		clients: map[int][]InboundClient{
+			1: {{ID: aliceUUID, Email: "alice", Enable: true}},
+			2: nil,
+		},
+	}
+	p.handle("/panel/inbound/list", func(*http.Request) (int, []byte) {
+		var inbounds []Inbound
+		for _, id := range []int{1, 2} {
+			settings, _ := json.Marshal(map[string]interface{}{"clients": p.clients[id], "decryption": "none"})
+			inbounds = append(inbounds, Inbound{
+				ID:             id,
+				Protocol:       "vless",
+				Settings:       string(settings),
+				StreamSettings: `{"network":"tcp","security":"reality"}`,
+			})
+		}
+		return apiSuccess(inbounds)
+	})
+	p.handlePrefix("/panel/api/inbounds/getClientTraffics/", func(r *http.Request) (int, []byte) {
+		email := strings.TrimPrefix(r.URL.Path, "/panel/api/inbounds/getClientTraffics/")
+		return apiSuccess(ClientStat{Email: email, Up: GiB, Down: 2 * GiB, Total: 10 * GiB})
+	})
+	p.handle("/panel/api/inbounds/addClient", func(r *http.Request) (int, []byte) {
+		var req AddClientRequest
+		var settings struct {
+			Clients []InboundClient `json:"clients"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal([]byte(req.Settings), &settings); err != nil {
+			t.Error(err)
+		}
+		p.clients[int(req.ID)] = append(p.clients[int(req.ID)], settings.Clients...)
+		return apiSuccess(nil)
+	})
+	for _, id := range []int{1, 2} {
+		p.handlePrefix("/panel/api/inbounds/"+strconv.Itoa(id)+"/delClient/", func(r *http.Request) (int, []byte) {
+			p.removeClient(id, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			return apiSuccess(nil)
+		})
+	}
+	p.handlePrefix("/panel/inbound/updateClient/", func(r *http.Request) (int, []byte) {
+		var settings struct {
+			Clients []InboundClient `json:"clients"`
+		}
+		if err := json.Unmarshal([]byte(r.FormValue("settings")), &settings); err != nil || len(settings.Clients) != 1 {
+			t.Errorf("Unexpected settings %q", r.FormValue("settings"))
+			return apiFailure("bad settings")
+		}
+		id, _ := strconv.Atoi(r.FormValue("id"))
+		p.removeClient(id, strings.TrimPrefix(r.URL.Path, "/panel/inbound/updateClient/"))
+		p.clients[id] = append(p.clients[id], settings.Clients[0])
+		return apiSuccess(nil)
+	})
+	return p
+}
+
+func (p *transferPanel) removeClient(inboundID int, uuid string) {
+	var kept []InboundClient
+	for _, client := range p.clients[inboundID] {
+		if client.ID != uuid {
+			kept = append(kept, client)
+		}
+	}
+	p.clients[inboundID] = kept
+}
+
+func (p *transferPanel) emails(inboundID int) []string {
+	var emails []string
+	for _, client := range p.clients[inboundID] {
+		emails = append(emails, client.Email)
+	}
+	return emails
+}
+
+func TestCopyClient(t *testing.T) {
+	p := newTransferPanel(t)
+	copied, err := p.client().CopyClient(context.Background(), "alice", 2, "alice-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Email != "alice-2" || copied.ID != aliceUUID || copied.Flow != VisionFlow || copied.TotalGB != 7*GiB {
+		t.Errorf("Unexpected copy %+v", copied)
+	}
+	if emails := p.emails(1); len(emails) != 1 || emails[0] != "alice" {
+		t.Errorf("Expected the source to keep alice, got %v", emails)
+	}
+	if emails := p.emails(2); len(emails) != 1 || emails[0] != "alice-2" {
+		t.Errorf("Expected alice-2 in the target, got %v", emails)
+	}
+}
+
+func TestMoveClient(t *testing.T) {
+	p := newTransferPanel(t)
+	moved, err := p.client().MoveClient(context.Background(), "alice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Email != "alice" || moved.ID != aliceUUID {
+		t.Errorf("Unexpected client %+v", moved)
+	}
+	if emails := p.emails(1); len(emails) != 0 {
+		t.Errorf("Expected the source to be empty, got %v", emails)
+	}
+	if emails := p.emails(2); len(emails) != 1 || emails[0] != "alice" {
+		t.Errorf("Expected alice renamed back in the target, got %v", emails)
+	}
+}
+
+func TestMoveClientAddFails(t *testing.T) {
+	p := newTransferPanel(t)
+	p.handle("/panel/api/inbounds/addClient", func(*http.Request) (int, []byte) {
+		return apiFailure("Duplicate email")
+	})
+
+	if _, err := p.client().MoveClient(context.Background(), "alice", 2); err == nil || !strings.Contains(err.Error(), "Duplicate email") {
+		t.Fatalf("Expected the add error, got %v", err)
+	}
+	if n := p.callCount("/panel/api/inbounds/1/delClient/" + aliceUUID); n != 0 {
+		t.Errorf("Expected the source to be untouched, got %d deletes", n)
+	}
+	if emails := p.emails(1); len(emails) != 1 || emails[0] != "alice" {
+		t.Errorf("Expected the source to keep alice, got %v", emails)
+	}
+}
+
+func TestMoveClientDeleteFails(t *testing.T) {
+	p := newTransferPanel(t)
+	p.handle("/panel/api/inbounds/1/delClient/"+aliceUUID, func(*http.Request) (int, []byte) {
+		return apiFailure("inbound is locked")
+	})
+
+	_, err := p.client().MoveClient(context.Background(), "alice", 2)
+	if err == nil || !strings.Contains(err.Error(), "inbound is locked") {
+		t.Fatalf("Expected the delete error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "left in inbound") {
+		t.Errorf("Expected the temporary client to be removed, got %v", err)
+	}
+	if n := p.callCount("/panel/api/inbounds/2/delClient/" + aliceUUID); n != 1 {
+		t.Errorf("Expected the temporary client to be deleted once, got %d", n)
+	}
+	if emails := p.emails(2); len(emails) != 0 {
+		t.Errorf("Expected the target to be empty, got %v", emails)
+	}
+	if emails := p.emails(1); len(emails) != 1 || emails[0] != "alice" {
+		t.Errorf("Expected the source to keep alice, got %v", emails)
+	}
+}
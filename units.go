@@ -0,0 +1,201 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const millisPerDay = int64(24 * time.Hour / time.Millisecond)
+
+// Expiry is an expiry time the way the panel stores it. Positive values are
+// Unix milliseconds, zero means the client or inbound never expires and
+// negative values are a delay in milliseconds that starts counting on first use.
+type Expiry int64
+
+// NoExpiry never expires.
+const NoExpiry Expiry = 0
+
+// ExpiresAt returns an absolute expiry at t.
+func ExpiresAt(t time.Time) Expiry {
+	return Expiry(t.UnixMilli())
+}
+
+// ExpiresAfterFirstUse returns an expiry that starts counting down the given
+// number of days once the client connects for the first time.
+func ExpiresAfterFirstUse(days int) Expiry {
+	return Expiry(-int64(days) * millisPerDay)
+}
+
+// Never reports whether e never expires.
+func (e Expiry) Never() bool {
+	return e == 0
+}
+
+// Delayed reports whether e only starts counting on first use.
+func (e Expiry) Delayed() bool {
+	return e < 0
+}
+
+// Time returns the absolute expiry time, or the zero time if e is not absolute.
+func (e Expiry) Time() time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(e))
+}
+
+// Delay returns the delayed-start duration, or zero if e is not delayed.
+func (e Expiry) Delay() time.Duration {
+	if e >= 0 {
+		return 0
+	}
+	return time.Duration(-int64(e)) * time.Millisecond
+}
+
+// DelayDays returns the delayed-start duration in whole days.
+func (e Expiry) DelayDays() int {
+	if e >= 0 {
+		return 0
+	}
+	return int(-int64(e) / millisPerDay)
+}
+
+// Expired reports whether an absolute expiry lies before now. Delayed and
+// never-expiring values are never expired.
+func (e Expiry) Expired(now time.Time) bool {
+	return e > 0 && !now.Before(e.Time())
+}
+
+func (e Expiry) String() string {
+	switch {
+	case e == 0:
+		return "never"
+	case e < 0:
+		if d := -int64(e); d%millisPerDay == 0 {
+			return fmt.Sprintf("%d days after first use", d/millisPerDay)
+		}
+		return e.Delay().String() + " after first use"
+	default:
+		return e.Time().UTC().Format(time.RFC3339)
+	}
+}
+
+// ByteSize is an amount of traffic in bytes. The panel uses it for traffic
+// counters and quotas, including the misleadingly named totalGB field.
+type ByteSize int64
+
+// Binary (IEC) byte size units.
+const (
+	Byte ByteSize = 1
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+	PiB           = 1024 * TiB
+)
+
+// Decimal (SI) byte size units.
+const (
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+	PB          = 1000 * TB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KiB,
+	"ki":  KiB,
+	"kib": KiB,
+	"kb":  KB,
+	"m":   MiB,
+	"mi":  MiB,
+	"mib": MiB,
+	"mb":  MB,
+	"g":   GiB,
+	"gi":  GiB,
+	"gib": GiB,
+	"gb":  GB,
+	"t":   TiB,
+	"ti":  TiB,
+	"tib": TiB,
+	"tb":  TB,
+	"p":   PiB,
+	"pi":  PiB,
+	"pib": PiB,
+	"pb":  PB,
+}
+
+// ParseByteSize parses sizes such as "50GiB", "1.5 TB" or "1024". IEC
+// suffixes and single letters (K, M, G, T, P) are binary, SI suffixes
+// (KB, MB, GB, ...) are decimal. Units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	str := strings.TrimSpace(s)
+	i := 0
+	for i < len(str) && (str[i] >= '0' && str[i] <= '9' || str[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid byte size %q: missing number", s)
+	}
+	num, err := strconv.ParseFloat(str[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", s, err)
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(str[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, strings.TrimSpace(str[i:]))
+	}
+	size := num * float64(unit)
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid byte size %q: out of range", s)
+	}
+	return ByteSize(math.Round(size)), nil
+}
+
+// String formats b using the largest binary unit that fits, e.g. "50GiB" or
+// "1.5TiB".
+func (b ByteSize) String() string {
+	units := []struct {
+		size ByteSize
+		name string
+	}{{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}}
+	abs := b
+	if abs < 0 {
+		abs = -abs
+	}
+	for _, u := range units {
+		if abs >= u.size {
+			v := strconv.FormatFloat(float64(b)/float64(u.size), 'f', 2, 64)
+			v = strings.TrimRight(strings.TrimRight(v, "0"), ".")
+			return v + u.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// GiBs returns b in gibibytes.
+func (b ByteSize) GiBs() float64 {
+	return float64(b) / float64(GiB)
+}
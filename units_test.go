@@ -0,0 +1,139 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	if !NoExpiry.Never() {
+		t.Error("Expected NoExpiry to never expire")
+	}
+
+	delayed := ExpiresAfterFirstUse(30)
+	if delayed != -2592000000 {
+		t.Errorf("Expected -2592000000, got %d", delayed)
+	}
+	if !delayed.Delayed() || delayed.DelayDays() != 30 {
+		t.Errorf("Expected delayed 30 days, got %v", delayed)
+	}
+	if !delayed.Time().IsZero() {
+		t.Errorf("Expected zero time for delayed expiry, got %v", delayed.Time())
+	}
+
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	abs := ExpiresAt(at)
+	if !abs.Time().Equal(at) {
+		t.Errorf("Expected %v, got %v", at, abs.Time())
+	}
+	if abs.Expired(at.Add(-time.Second)) || !abs.Expired(at) {
+		t.Error("Unexpected Expired result")
+	}
+	if abs.String() != "2025-01-02T03:04:05Z" {
+		t.Errorf("Unexpected string %q", abs.String())
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ByteSize
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"50GiB", 50 * GiB},
+		{"50 gib", 50 * GiB},
+		{"50G", 50 * GiB},
+		{"50GB", 50 * GB},
+		{"1.5TiB", TiB + 512*GiB},
+		{"512k", 512 * KiB},
+		{"8191PiB", 8191 * PiB},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "GiB", "10XB", "1..2G", "8192PiB", "9223372036854775808"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) expected error", in)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		in   ByteSize
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{50 * GiB, "50GiB"},
+		{TiB + 512*GiB, "1.5TiB"},
+		{1536 * KiB, "1.5MiB"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestTypedClientJSON(t *testing.T) {
+	client := XrayClient{
+		ID:         "uuid",
+		Email:      "user",
+		TotalGB:    50 * GiB,
+		ExpiryTime: ExpiresAfterFirstUse(7),
+		Enable:     true,
+	}
+	data, err := json.Marshal(client)
+	if err != nil {
+		t.Fatalf("Failed to marshal XrayClient: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if raw["totalGB"] != float64(53687091200) {
+		t.Errorf("Expected totalGB 53687091200, got %v", raw["totalGB"])
+	}
+	if raw["expiryTime"] != float64(-604800000) {
+		t.Errorf("Expected expiryTime -604800000, got %v", raw["expiryTime"])
+	}
+
+	var inbound Inbound
+	err = json.Unmarshal([]byte(`{"id":1,"up":5497558138880,"down":1,"total":0,"expiryTime":1735787045000}`), &inbound)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal Inbound: %v", err)
+	}
+	if inbound.Up != 5*TiB {
+		t.Errorf("Expected up 5TiB, got %v", inbound.Up)
+	}
+	if inbound.ExpiryTime.Time().UnixMilli() != 1735787045000 {
+		t.Errorf("Unexpected expiry %v", inbound.ExpiryTime)
+	}
+}
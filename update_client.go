@@ -39,15 +39,35 @@ func (c *Client) DeleteClient(ctx context.Context, inboundId uint, clientUuid st
 	return resp, err
 }
 
+// ClientKey returns the identifier the panel uses for a client in API paths:
+// the password for trojan, the email for shadowsocks and the UUID otherwise.
+func ClientKey(protocol string, client InboundClient) string {
+	switch protocol {
+	case "trojan":
+		return client.Password
+	case "shadowsocks":
+		return client.Email
+	default:
+		return client.ID
+	}
+}
+
 func (c *Client) UpdateClient(ctx context.Context, inboundId uint, client InboundClient) (*ApiResponse, error) {
+	return c.updateClient(ctx, inboundId, client.ID, client)
+}
+
+// updateClient replaces the client identified by key, which may differ from
+// the key of the new client data. client is an InboundClient or the raw
+// JSON object of a client.
+func (c *Client) updateClient(ctx context.Context, inboundId uint, key string, client interface{}) (*ApiResponse, error) {
 	resp := &ApiResponse{}
 	inboundIdStr := strconv.FormatUint(uint64(inboundId), 10)
 
 	// Create client settings using InboundClient struct
 	clientSettings := struct {
-		Clients []InboundClient `json:"clients"`
+		Clients []interface{} `json:"clients"`
 	}{
-		Clients: []InboundClient{client},
+		Clients: []interface{}{client},
 	}
 
 	// Convert settings to JSON string
@@ -61,7 +81,7 @@ func (c *Client) UpdateClient(ctx context.Context, inboundId uint, client Inboun
 	form.Add("id", inboundIdStr)
 	form.Add("settings", string(settingsBytes))
 
-	err = c.DoForm(ctx, http.MethodPost, "/panel/inbound/updateClient/"+client.ID, form, resp)
+	err = c.DoForm(ctx, http.MethodPost, "/panel/inbound/updateClient/"+url.PathEscape(key), form, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,143 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// XrayStateError is returned when Xray doesn't reach the wanted state.
+type XrayStateError struct {
+	Want ProcessState
+	// Last state seen, empty if the status couldn't be read.
+	State    ProcessState
+	ErrorMsg string
+	// Output of GetXrayResult, usually the tail of Xray's error output.
+	Result string
+	// Why waiting stopped, e.g. context.DeadlineExceeded. Nil if Xray
+	// reached the Error state.
+	Err error
+}
+
+func (e *XrayStateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "xray did not reach state %s", e.Want)
+	if e.State != "" {
+		fmt.Fprintf(&b, ", state is %s", e.State)
+	}
+	if e.ErrorMsg != "" {
+		fmt.Fprintf(&b, ": %s", e.ErrorMsg)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, " (%v)", e.Err)
+	}
+	return b.String()
+}
+
+func (e *XrayStateError) Unwrap() error {
+	return e.Err
+}
+
+// StopXray stops Xray and waits until it is stopped.
+func (c *Client) StopXray(ctx context.Context) error {
+	if err := c.postXrayService(ctx, "/server/stopXrayService"); err != nil {
+		return err
+	}
+	_, err := c.waitForXrayState(ctx, Stop, true)
+	return err
+}
+
+// StartXray starts Xray and waits until it is running. The panel starts a
+// stopped Xray through its restart endpoint.
+func (c *Client) StartXray(ctx context.Context) error {
+	return c.RestartXray(ctx)
+}
+
+// RestartXray restarts Xray and waits until it is running, unlike
+// RestartXrayService which returns right away.
+func (c *Client) RestartXray(ctx context.Context) error {
+	if err := c.postXrayService(ctx, "/server/restartXrayService"); err != nil {
+		return err
+	}
+	_, err := c.waitForXrayState(ctx, Running, true)
+	return err
+}
+
+// WaitForXrayState polls ServerStatus until Xray reaches state. It fails
+// early if Xray is in the Error state while waiting for another state. On
+// failure the error is an *XrayStateError.
+func (c *Client) WaitForXrayState(ctx context.Context, state ProcessState) (*ServerStatus, error) {
+	return c.waitForXrayState(ctx, state, false)
+}
+
+// waitForXrayState is WaitForXrayState. With afterCall the first poll may
+// still see Xray from before the call, e.g. an old Error or the process
+// being restarted, so states only count from the next poll on.
+func (c *Client) waitForXrayState(ctx context.Context, state ProcessState, afterCall bool) (*ServerStatus, error) {
+	polled := false
+	status, err := c.waitForXray(ctx, func(x XrayStatus) (bool, error) {
+		if afterCall && !polled {
+			polled = true
+			return false, nil
+		}
+		if x.State == Error && state != Error {
+			return false, fmt.Errorf("xray is in the error state")
+		}
+		return x.State == state, nil
+	})
+	if err == nil {
+		return status, nil
+	}
+
+	stateErr := &XrayStateError{Want: state}
+	if status != nil {
+		stateErr.State = status.Xray.State
+		stateErr.ErrorMsg = status.Xray.ErrorMsg
+		if stateErr.State != Error {
+			stateErr.Err = err
+		}
+	} else {
+		stateErr.Err = err
+	}
+	// ctx may be done, the result is only context for the error.
+	resultCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if resp, err := c.GetXrayResult(resultCtx); err == nil {
+		var result string
+		if json.Unmarshal(resp.Obj, &result) == nil {
+			stateErr.Result = result
+		}
+	}
+	return status, stateErr
+}
+
+func (c *Client) postXrayService(ctx context.Context, path string) error {
+	resp := &ApiResponse{}
+	err := c.Do(ctx, http.MethodPost, path, nil, resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestXrayStateError(t *testing.T) {
+	var err error = &XrayStateError{
+		Want:     Running,
+		State:    Error,
+		ErrorMsg: "failed to load config",
+		Result:   "Failed to start: main: failed to load config files",
+	}
+	if err.Error() != "xray did not reach state running, state is error: failed to load config" {
+		t.Errorf("Unexpected message %q", err)
+	}
+
+	err = &XrayStateError{Want: Stop, State: Running, Err: context.DeadlineExceeded}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the error to wrap context.DeadlineExceeded")
+	}
+	var stateErr *XrayStateError
+	if !errors.As(err, &stateErr) || stateErr.State != Running {
+		t.Errorf("Expected *XrayStateError, got %v", err)
+	}
+}
+
+func TestWaitForXrayStateImmediate(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleStatuses(XrayStatus{State: Running, Version: "25.1.30"})
+
+	status, err := p.client().WaitForXrayState(context.Background(), Running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Xray.Version != "25.1.30" || p.callCount("/server/status") != 1 {
+		t.Errorf("Expected one poll, got %d with %+v", p.callCount("/server/status"), status.Xray)
+	}
+}
+
+func TestWaitForXrayStateError(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleStatuses(
+		XrayStatus{State: Stop},
+		XrayStatus{State: Error, ErrorMsg: "failed to load config"},
+	)
+	p.handleObj("/panel/xray/getXrayResult", "Failed to start: main: failed to load config files")
+	p.handleObj("/server/restartXrayService", nil)
+
+	err := p.client().StartXray(context.Background())
+	var stateErr *XrayStateError
+	if !errors.As(err, &stateErr) {
+		t.Fatalf("Expected *XrayStateError, got %v", err)
+	}
+	if stateErr.Want != Running || stateErr.State != Error || stateErr.ErrorMsg != "failed to load config" || stateErr.Err != nil {
+		t.Errorf("Unexpected error %+v", stateErr)
+	}
+	if stateErr.Result != "Failed to start: main: failed to load config files" {
+		t.Errorf("Expected the Xray result, got %q", stateErr.Result)
+	}
+	if n := p.callCount("/server/restartXrayService"); n != 1 {
+		t.Errorf("Expected Xray to be started once, got %d", n)
+	}
+}
+
+func TestStartXrayIgnoresStaleError(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleStatuses(
+		XrayStatus{State: Error, ErrorMsg: "failed to load config"},
+		XrayStatus{State: Running},
+	)
+	p.handleObj("/server/restartXrayService", nil)
+
+	if err := p.client().StartXray(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.callCount("/server/status"); n != 2 {
+		t.Errorf("Expected 2 polls, got %d", n)
+	}
+}
+
+func TestRestartXraySkipsFirstPoll(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleStatuses(XrayStatus{State: Running})
+	p.handleObj("/server/restartXrayService", nil)
+
+	if err := p.client().RestartXray(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.callCount("/server/status"); n != 2 {
+		t.Errorf("Expected the first poll to be skipped, got %d polls", n)
+	}
+}
+
+func TestWaitForXrayStateTimeout(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleStatuses(XrayStatus{State: Running})
+	p.handleObj("/panel/xray/getXrayResult", "")
+	p.handleObj("/server/stopXrayService", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := p.client().StopXray(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be wrapped, got %v", err)
+	}
+	var stateErr *XrayStateError
+	if !errors.As(err, &stateErr) || stateErr.Want != Stop || stateErr.State != Running {
+		t.Errorf("Unexpected error %+v", err)
+	}
+	// The result is fetched even though ctx is done.
+	if p.callCount("/panel/xray/getXrayResult") != 1 {
+		t.Error("Expected the Xray result to be fetched")
+	}
+}
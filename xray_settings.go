@@ -22,8 +22,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
 // XraySettings represents the complete Xray configuration
@@ -54,19 +52,24 @@ type XrayAPI struct {
 
 // XrayInbound represents inbound configuration
 type XrayInbound struct {
-	Tag      string                 `json:"tag,omitempty"`
-	Listen   string                 `json:"listen,omitempty"`
-	Port     int                    `json:"port,omitempty"`
-	Protocol string                 `json:"protocol,omitempty"`
-	Settings map[string]interface{} `json:"settings,omitempty"`
+	Tag            string                 `json:"tag,omitempty"`
+	Listen         string                 `json:"listen,omitempty"`
+	Port           int                    `json:"port,omitempty"`
+	Protocol       string                 `json:"protocol,omitempty"`
+	Settings       map[string]interface{} `json:"settings,omitempty"`
+	StreamSettings map[string]interface{} `json:"streamSettings,omitempty"`
+	Sniffing       map[string]interface{} `json:"sniffing,omitempty"`
 }
 
 // XrayOutbound represents outbound configuration
 type XrayOutbound struct {
 	Tag            string                 `json:"tag,omitempty"`
 	Protocol       string                 `json:"protocol,omitempty"`
+	SendThrough    string                 `json:"sendThrough,omitempty"`
 	Settings       map[string]interface{} `json:"settings,omitempty"`
 	StreamSettings map[string]interface{} `json:"streamSettings,omitempty"`
+	ProxySettings  map[string]interface{} `json:"proxySettings,omitempty"`
+	Mux            map[string]interface{} `json:"mux,omitempty"`
 }
 
 // OutboundSettings represents different types of outbound settings
@@ -136,17 +139,28 @@ type XrayPolicy struct {
 
 // XrayRouting represents routing configuration
 type XrayRouting struct {
-	DomainStrategy string     `json:"domainStrategy,omitempty"`
-	Rules          []XrayRule `json:"rules,omitempty"`
+	DomainStrategy string                   `json:"domainStrategy,omitempty"`
+	Rules          []XrayRule               `json:"rules,omitempty"`
+	Balancers      []map[string]interface{} `json:"balancers,omitempty"`
 }
 
 // XrayRule represents a routing rule
 type XrayRule struct {
-	Type        string   `json:"type"`
-	InboundTag  []string `json:"inboundTag,omitempty"`
-	OutboundTag string   `json:"outboundTag,omitempty"`
-	IP          []string `json:"ip,omitempty"`
-	Protocol    []string `json:"protocol,omitempty"`
+	Type    string   `json:"type,omitempty"`
+	RuleTag string   `json:"ruleTag,omitempty"`
+	Domain  []string `json:"domain,omitempty"`
+	IP      []string `json:"ip,omitempty"`
+	// Port and SourcePort are a number or a string such as "53,443,1000-2000".
+	Port        interface{}       `json:"port,omitempty"`
+	SourcePort  interface{}       `json:"sourcePort,omitempty"`
+	Network     string            `json:"network,omitempty"`
+	Source      []string          `json:"source,omitempty"`
+	User        []string          `json:"user,omitempty"`
+	InboundTag  []string          `json:"inboundTag,omitempty"`
+	Protocol    []string          `json:"protocol,omitempty"`
+	Attrs       map[string]string `json:"attrs,omitempty"`
+	OutboundTag string            `json:"outboundTag,omitempty"`
+	BalancerTag string            `json:"balancerTag,omitempty"`
 }
 
 // XraySettingsResponse represents the response from getting Xray settings
@@ -233,8 +247,14 @@ func (c *Client) RestartXrayService(ctx context.Context) (*ApiResponse, error) {
 	return resp, nil
 }
 
-// ParseVlessURL parses a VLESS URL string into an XrayOutbound
+// ParseVlessURL parses a VLESS URL string into an XrayOutbound. Unknown
+// transport types such as quic and security values are copied through
+// as is; ParseShareLink rejects them.
 func ParseVlessURL(vlessURL string) (*XrayOutbound, error) {
+	return parseVlessURL(vlessURL, false)
+}
+
+func parseVlessURL(vlessURL string, strict bool) (*XrayOutbound, error) {
 	// Parse the URL
 	u, err := url.Parse(vlessURL)
 	if err != nil {
@@ -253,14 +273,9 @@ func ParseVlessURL(vlessURL string) (*XrayOutbound, error) {
 	}
 
 	// Extract host and port
-	host := u.Hostname()
-	portStr := u.Port()
-	if portStr == "" {
-		portStr = "443" // Default port
-	}
-	port, err := strconv.Atoi(portStr)
+	host, port, err := parseHostPort(u, "443")
 	if err != nil {
-		return nil, fmt.Errorf("invalid port: %w", err)
+		return nil, fmt.Errorf("invalid VLESS URL: %w", err)
 	}
 
 	// Parse query parameters
@@ -279,7 +294,7 @@ func ParseVlessURL(vlessURL string) (*XrayOutbound, error) {
 						{
 							"id":         uuid,
 							"flow":       params.Get("flow"),
-							"encryption": "none",
+							"encryption": valueOr(params.Get("encryption"), "none"),
 						},
 					},
 				},
@@ -288,57 +303,9 @@ func ParseVlessURL(vlessURL string) (*XrayOutbound, error) {
 	}
 
 	// Build stream settings
-	streamSettings := map[string]interface{}{
-		"network": params.Get("type"),
-	}
-
-	// Handle security settings
-	security := params.Get("security")
-	if security != "" {
-		streamSettings["security"] = security
-
-		switch security {
-		case "reality":
-			realitySettings := map[string]interface{}{
-				"publicKey":   params.Get("pbk"),
-				"fingerprint": params.Get("fp"),
-				"serverName":  params.Get("sni"),
-				"shortId":     params.Get("sid"),
-				"spiderX":     params.Get("spx"),
-			}
-			streamSettings["realitySettings"] = realitySettings
-		case "tls":
-			tlsSettings := map[string]interface{}{
-				"serverName": params.Get("sni"),
-			}
-			if alpn := params.Get("alpn"); alpn != "" {
-				tlsSettings["alpn"] = strings.Split(alpn, ",")
-			}
-			streamSettings["tlsSettings"] = tlsSettings
-		}
-	}
-
-	// Handle TCP settings
-	if params.Get("type") == "tcp" {
-		headerType := params.Get("headerType")
-		if headerType == "" {
-			headerType = "none"
-		}
-		tcpSettings := map[string]interface{}{
-			"header": map[string]interface{}{
-				"type": headerType,
-			},
-		}
-		if headerType == "http" {
-			if host := params.Get("host"); host != "" {
-				tcpSettings["header"].(map[string]interface{})["request"] = map[string]interface{}{
-					"headers": map[string]interface{}{
-						"Host": strings.Split(host, ","),
-					},
-				}
-			}
-		}
-		streamSettings["tcpSettings"] = tcpSettings
+	streamSettings, err := streamSettingsFromParams(params, "", strict)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VLESS URL: %w", err)
 	}
 
 	outbound.StreamSettings = streamSettings
@@ -179,6 +179,45 @@ func TestParseVlessURL(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Unknown transport is copied through",
+			url:  "vless://uuid-here@example.com:443?type=quic&security=tls&sni=example.com#quic",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				if outbound.StreamSettings["network"] != "quic" {
+					t.Errorf("Expected network 'quic', got '%v'", outbound.StreamSettings["network"])
+				}
+				if _, ok := outbound.StreamSettings["tcpSettings"]; ok {
+					t.Error("Unexpected tcpSettings")
+				}
+				if outbound.StreamSettings["security"] != "tls" {
+					t.Errorf("Expected security 'tls', got '%v'", outbound.StreamSettings["security"])
+				}
+			},
+		},
+		{
+			name: "Reality without public key",
+			url:  "vless://uuid-here@example.com:443?type=h2&security=reality&sni=example.com",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				if outbound.StreamSettings["network"] != "h2" {
+					t.Errorf("Expected network 'h2', got '%v'", outbound.StreamSettings["network"])
+				}
+				if outbound.StreamSettings["security"] != "reality" {
+					t.Errorf("Expected security 'reality', got '%v'", outbound.StreamSettings["security"])
+				}
+			},
+		},
+		{
+			name: "Unknown security is copied through",
+			url:  "vless://uuid-here@example.com:443?security=xtls",
+			check: func(t *testing.T, outbound *XrayOutbound) {
+				if outbound.StreamSettings["security"] != "xtls" {
+					t.Errorf("Expected security 'xtls', got '%v'", outbound.StreamSettings["security"])
+				}
+				if _, ok := outbound.StreamSettings["tcpSettings"]; ok {
+					t.Error("Unexpected tcpSettings")
+				}
+			},
+		},
 		{
 			name:    "Invalid scheme",
 			url:     "vmess://uuid@example.com:443",
@@ -0,0 +1,161 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultXrayWaitTimeout bounds waiting for the Xray process when ctx has
+// no deadline.
+const DefaultXrayWaitTimeout = 2 * time.Minute
+
+// xrayPollInterval is how often ServerStatus is polled while waiting.
+var xrayPollInterval = time.Second
+
+// ErrXrayInstallFailed is returned when Xray doesn't start after installing
+// a version.
+var ErrXrayInstallFailed = errors.New("xray failed to start after install")
+
+// GetXrayVersions lists the Xray versions the panel can install, newest
+// first, e.g. "v25.1.30".
+func (c *Client) GetXrayVersions(ctx context.Context) ([]string, error) {
+	resp := &ApiResponse{}
+	err := c.Do(ctx, http.MethodPost, "/server/getXrayVersion", nil, resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%s", resp.Msg)
+	}
+	var versions []string
+	if err := json.Unmarshal(resp.Obj, &versions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal versions: %w", err)
+	}
+	return versions, nil
+}
+
+// InstallXrayVersion installs an Xray version and waits until Xray runs it.
+// If Xray ends up in the Error state the previous version is reinstalled
+// and an error wrapping ErrXrayInstallFailed is returned.
+func (c *Client) InstallXrayVersion(ctx context.Context, version string) error {
+	status, err := c.ServerStatus(ctx)
+	if err != nil {
+		return err
+	}
+	previous := status.Xray.Version
+
+	if err := c.installXray(ctx, version); err != nil {
+		return err
+	}
+	// The first polls may still see the process from before the install,
+	// so an Error state only counts once the state or version changed.
+	before := status.Xray
+	changed := false
+	_, err = c.waitForXray(ctx, func(x XrayStatus) (bool, error) {
+		if x.State != before.State || !sameXrayVersion(x.Version, before.Version) {
+			changed = true
+		}
+		switch {
+		case x.State == Running && sameXrayVersion(x.Version, version):
+			return true, nil
+		case x.State == Error && changed:
+			return false, fmt.Errorf("%w: %s", ErrXrayInstallFailed, x.ErrorMsg)
+		}
+		return false, nil
+	})
+	if err == nil || !errors.Is(err, ErrXrayInstallFailed) || previous == "" {
+		return err
+	}
+
+	installErr := err
+	if err := c.installXray(ctx, "v"+strings.TrimPrefix(previous, "v")); err != nil {
+		return fmt.Errorf("%w, rollback to %s failed: %v", installErr, previous, err)
+	}
+	_, err = c.waitForXray(ctx, func(x XrayStatus) (bool, error) {
+		return x.State == Running && sameXrayVersion(x.Version, previous), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w, rollback to %s failed: %v", installErr, previous, err)
+	}
+	return fmt.Errorf("%w, rolled back to %s", installErr, previous)
+}
+
+func (c *Client) installXray(ctx context.Context, version string) error {
+	resp := &ApiResponse{}
+	err := c.Do(ctx, http.MethodPost, "/server/installXray/"+url.PathEscape(version), nil, resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Msg)
+	}
+	return nil
+}
+
+// sameXrayVersion compares versions with or without the "v" prefix.
+func sameXrayVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// waitForXray polls ServerStatus until done reports true or an error. It
+// gives up when ctx is done, or after DefaultXrayWaitTimeout if ctx has no
+// deadline. Failed polls are retried since the panel may be busy
+// restarting Xray.
+func (c *Client) waitForXray(ctx context.Context, done func(XrayStatus) (bool, error)) (*ServerStatus, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultXrayWaitTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(xrayPollInterval)
+	defer ticker.Stop()
+
+	var last *ServerStatus
+	var lastErr error
+	for {
+		status, err := c.ServerStatus(ctx)
+		if err == nil {
+			last, lastErr = status, nil
+			ok, err := done(status.Xray)
+			if err != nil || ok {
+				return status, err
+			}
+		} else {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return last, fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+			}
+			if last != nil {
+				return last, fmt.Errorf("%w: xray is %s, version %s", ctx.Err(), last.Xray.State, last.Xray.Version)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSameXrayVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"25.1.30", "v25.1.30", true},
+		{"v25.1.30", "v25.1.30", true},
+		{"25.1.30", "25.1.1", false},
+		{"", "v25.1.30", false},
+	}
+	for _, test := range tests {
+		if same := sameXrayVersion(test.a, test.b); same != test.same {
+			t.Errorf("sameXrayVersion(%q, %q) = %v", test.a, test.b, same)
+		}
+	}
+}
+
+func TestInstallXrayVersion(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleObj("/server/installXray/v25.1.30", nil)
+	// Xray was broken before, the first poll still sees that.
+	p.handleStatuses(
+		XrayStatus{State: Error, Version: "1.8.24", ErrorMsg: "old failure"},
+		XrayStatus{State: Error, Version: "1.8.24", ErrorMsg: "old failure"},
+		XrayStatus{State: Running, Version: "1.8.24"},
+		XrayStatus{State: Running, Version: "25.1.30"},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.client().InstallXrayVersion(ctx, "v25.1.30"); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.callCount("/server/installXray/v1.8.24"); n != 0 {
+		t.Errorf("Expected no rollback, got %d", n)
+	}
+}
+
+func TestInstallXrayVersionRollback(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleObj("/server/installXray/v25.1.30", nil)
+	p.handleObj("/server/installXray/v1.8.24", nil)
+	p.handleStatuses(
+		XrayStatus{State: Running, Version: "1.8.24"},
+		XrayStatus{State: Running, Version: "1.8.24"},
+		XrayStatus{State: Error, Version: "25.1.30", ErrorMsg: "failed to load config"},
+		XrayStatus{State: Stop, Version: "1.8.24"},
+		XrayStatus{State: Running, Version: "1.8.24"},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := p.client().InstallXrayVersion(ctx, "v25.1.30")
+	if !errors.Is(err, ErrXrayInstallFailed) || !strings.Contains(err.Error(), "rolled back to 1.8.24") {
+		t.Errorf("Expected a rolled back install, got %v", err)
+	}
+	if n := p.callCount("/server/installXray/v1.8.24"); n != 1 {
+		t.Errorf("Expected one rollback, got %d", n)
+	}
+}
+
+func TestInstallXrayVersionRollbackFails(t *testing.T) {
+	fastXrayPolls(t)
+	p := newFakePanel(t)
+	p.handleObj("/server/installXray/v25.1.30", nil)
+	p.handle("/server/installXray/v1.8.24", func(*http.Request) (int, []byte) {
+		return apiFailure("download failed")
+	})
+	p.handleStatuses(
+		XrayStatus{State: Running, Version: "1.8.24"},
+		XrayStatus{State: Error, Version: "25.1.30", ErrorMsg: "failed to load config"},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := p.client().InstallXrayVersion(ctx, "v25.1.30")
+	if !errors.Is(err, ErrXrayInstallFailed) || !strings.Contains(err.Error(), "rollback to 1.8.24 failed: download failed") {
+		t.Errorf("Expected a failed rollback, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+/* Copyright 2024 İrem Kuyucu <irem@digilol.net>
+ * Copyright 2024 Laurynas Četyrkinas <laurynas@digilol.net>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client3xui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlField is a key of a yamlMap.
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+// yamlMap is a YAML mapping that keeps its key order. Values are strings,
+// ints, bools, []string, yamlMap or []yamlMap.
+type yamlMap []yamlField
+
+// set appends a field unless value is empty.
+func (m *yamlMap) set(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case []string:
+		if len(v) == 0 {
+			return
+		}
+	case yamlMap:
+		if len(v) == 0 {
+			return
+		}
+	}
+	*m = append(*m, yamlField{key, value})
+}
+
+// encodeYAML writes m as a block style YAML document.
+func encodeYAML(m yamlMap) []byte {
+	var b strings.Builder
+	writeYAMLMap(&b, m, 0, false)
+	return []byte(b.String())
+}
+
+// writeYAMLMap writes the fields of m at indent. If inline, the first field
+// continues the current line, after a "- " sequence marker.
+func writeYAMLMap(b *strings.Builder, m yamlMap, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+	for i, f := range m {
+		if !inline || i > 0 {
+			b.WriteString(pad)
+		}
+		b.WriteString(yamlScalar(f.key))
+		b.WriteString(":")
+		switch v := f.value.(type) {
+		case yamlMap:
+			if len(v) == 0 {
+				b.WriteString(" {}\n")
+				continue
+			}
+			b.WriteString("\n")
+			writeYAMLMap(b, v, indent+2, false)
+		case []yamlMap:
+			if len(v) == 0 {
+				b.WriteString(" []\n")
+				continue
+			}
+			b.WriteString("\n")
+			for _, item := range v {
+				b.WriteString(pad + "  - ")
+				writeYAMLMap(b, item, indent+4, true)
+			}
+		case []string:
+			if len(v) == 0 {
+				b.WriteString(" []\n")
+				continue
+			}
+			b.WriteString("\n")
+			for _, item := range v {
+				b.WriteString(pad + "  - " + yamlScalar(item) + "\n")
+			}
+		default:
+			b.WriteString(" " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+// yamlScalar formats a scalar, double quoting strings that could be read
+// as something else.
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if yamlPlainSafe(v) {
+			return v
+		}
+		// JSON strings are valid YAML double quoted scalars.
+		quoted, _ := json.Marshal(v)
+		return string(quoted)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		panic(fmt.Sprintf("unsupported YAML value %T", v))
+	}
+}
+
+func yamlPlainSafe(s string) bool {
+	if s == "" {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return false
+	}
+	c := s[0]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '/' || c == '_') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '/') {
+			return false
+		}
+	}
+	return true
+}